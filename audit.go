@@ -0,0 +1,381 @@
+package mirrorcat
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// MappingChange names the way in which a mapping was changed, for AuditRecords which describe a mapping rather than
+// a push.
+type MappingChange string
+
+// These are the changes to a mapping which are recorded in the audit log.
+const (
+	MappingAdded   MappingChange = "added"
+	MappingRemoved MappingChange = "removed"
+	MappingExpired MappingChange = "expired"
+)
+
+// AuditRecord describes a single attempt to move a mirror's branch, or a change to a mapping, for as long as it must
+// be kept for compliance.
+type AuditRecord struct {
+	Time time.Time `json:"time"`
+
+	// DeliveryID and Pusher identify the webhook delivery that caused the push, and who pushed to the original. Both
+	// are empty when MirrorCat pushed on its own, like when a mirror was added to Redis.
+	DeliveryID string `json:"delivery,omitempty"`
+	Pusher     string `json:"pusher,omitempty"`
+
+	// JobID is the ID of the Job that recorded the same push in a JobHistory.
+	JobID string `json:"job,omitempty"`
+
+	// Original and Mirror should not include credentials.
+	Original RemoteRef `json:"original"`
+	Mirror   RemoteRef `json:"mirror"`
+
+	// OldSHA and NewSHA are the commits that the mirror's branch pointed to before and after the push. See
+	// `PushResult` for when they are empty.
+	OldSHA string `json:"old_sha,omitempty"`
+	NewSHA string `json:"new_sha,omitempty"`
+
+	Outcome JobStatus `json:"outcome"`
+	Error   string    `json:"error,omitempty"`
+
+	// Source is the file in a mappings directory that the mapping from Original to Mirror was read from, if it was.
+	Source string `json:"source,omitempty"`
+
+	// Change is only set on records which describe a mapping from Original to Mirror being changed in Redis, rather
+	// than a push. Key is the Redis key which was changed, and Exclusion is true if the mapping was an exclusion.
+	Change    MappingChange `json:"change,omitempty"`
+	Key       string        `json:"key,omitempty"`
+	Exclusion bool          `json:"exclusion,omitempty"`
+}
+
+// AuditSink durably stores AuditRecords.
+type AuditSink interface {
+	Audit(ctx context.Context, record AuditRecord) error
+}
+
+// AuditReader is implemented by AuditSinks which are able to read back the records they have stored.
+type AuditReader interface {
+	ReadAudit(ctx context.Context, query AuditQuery) ([]AuditRecord, error)
+}
+
+// AuditSinks stores each record in every one of its sinks.
+type AuditSinks []AuditSink
+
+// Audit stores `record` in each sink, even if some of them fail. The first error encountered is returned.
+func (sinks AuditSinks) Audit(ctx context.Context, record AuditRecord) (err error) {
+	for _, sink := range sinks {
+		if current := sink.Audit(ctx, record); current != nil && err == nil {
+			err = current
+		}
+	}
+	return
+}
+
+// AuditQuery selects the records returned by `AuditReader.ReadAudit`. Fields which are left as their zero value
+// select every record.
+type AuditQuery struct {
+	// Repository and Ref match records whose original or mirror has them.
+	Repository string
+	Ref        string
+
+	DeliveryID string
+	Pusher     string
+	Outcome    *JobStatus
+
+	// Since and Until match records made within a range of time.
+	Since time.Time
+	Until time.Time
+
+	// Limit is the largest number of records to return, most recent first. Zero returns every record.
+	Limit int
+}
+
+// Matches determines whether a record is selected by the query.
+func (query AuditQuery) Matches(record AuditRecord) bool {
+	if !matchesEitherSide(record.Original, record.Mirror, query.Repository, query.Ref) {
+		return false
+	}
+	if query.DeliveryID != "" && record.DeliveryID != query.DeliveryID {
+		return false
+	}
+	if query.Pusher != "" && record.Pusher != query.Pusher {
+		return false
+	}
+	if query.Outcome != nil && record.Outcome != *query.Outcome {
+		return false
+	}
+	if !query.Since.IsZero() && record.Time.Before(query.Since) {
+		return false
+	}
+	if !query.Until.IsZero() && record.Time.After(query.Until) {
+		return false
+	}
+	return true
+}
+
+// full determines whether `found` already holds as many records as the query allows.
+func (query AuditQuery) full(found []AuditRecord) bool {
+	return query.Limit > 0 && len(found) >= query.Limit
+}
+
+// FileAuditSink appends each record as a line of JSON to "audit.jsonl" in `Dir`. Once that file would grow beyond
+// `MaxBytes` it is renamed to include the time it was rotated, and a new file is started. Only the `MaxFiles` most
+// recently rotated files are kept.
+//
+// A `MaxBytes` of zero never rotates, and a `MaxFiles` of zero keeps every rotated file.
+type FileAuditSink struct {
+	Dir      string
+	MaxBytes int64
+	MaxFiles int
+
+	sync.Mutex
+}
+
+const (
+	auditFilePrefix = "audit"
+	auditFileSuffix = ".jsonl"
+)
+
+// NewFileAuditSink creates a FileAuditSink which writes to `dir`, creating it if necessary.
+func NewFileAuditSink(dir string, maxBytes int64, maxFiles int) (*FileAuditSink, error) {
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, err
+	}
+
+	return &FileAuditSink{
+		Dir:      dir,
+		MaxBytes: maxBytes,
+		MaxFiles: maxFiles,
+	}, nil
+}
+
+func (fas *FileAuditSink) current() string {
+	return filepath.Join(fas.Dir, auditFilePrefix+auditFileSuffix)
+}
+
+// Audit appends `record` to the current file, and waits for it to be written to disk.
+func (fas *FileAuditSink) Audit(ctx context.Context, record AuditRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	fas.Lock()
+	defer fas.Unlock()
+
+	if info, err := os.Stat(fas.current()); err == nil && fas.MaxBytes > 0 && info.Size() > 0 && info.Size()+int64(len(line)) > fas.MaxBytes {
+		if err = fas.rotate(); err != nil {
+			return err
+		}
+	}
+
+	handle, err := os.OpenFile(fas.current(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0640)
+	if err != nil {
+		return err
+	}
+	defer handle.Close()
+
+	if _, err = handle.Write(line); err != nil {
+		return err
+	}
+	return handle.Sync()
+}
+
+// rotate renames the current file, then removes the oldest rotated files beyond `MaxFiles`.
+func (fas *FileAuditSink) rotate() error {
+	rotated := filepath.Join(fas.Dir, fmt.Sprintf("%s-%s%s", auditFilePrefix, time.Now().UTC().Format("20060102T150405.000000000Z"), auditFileSuffix))
+	if err := os.Rename(fas.current(), rotated); err != nil {
+		return err
+	}
+
+	if fas.MaxFiles <= 0 {
+		return nil
+	}
+
+	files, err := fas.rotatedFiles()
+	if err != nil {
+		return err
+	}
+	for len(files) > fas.MaxFiles {
+		if err = os.Remove(files[0]); err != nil {
+			return err
+		}
+		files = files[1:]
+	}
+	return nil
+}
+
+// rotatedFiles lists every file that has been rotated, oldest first.
+func (fas *FileAuditSink) rotatedFiles() ([]string, error) {
+	entries, err := ioutil.ReadDir(fas.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, auditFilePrefix+"-") || !strings.HasSuffix(name, auditFileSuffix) {
+			continue
+		}
+		files = append(files, filepath.Join(fas.Dir, name))
+	}
+
+	// Rotated files are named by the time they were rotated, so that sorting them by name also sorts them by age.
+	sort.Strings(files)
+	return files, nil
+}
+
+// ReadAudit finds the records selected by `query`, most recent first. Lines which are not valid JSON, like one that
+// was only partially written before MirrorCat was stopped, are skipped.
+func (fas *FileAuditSink) ReadAudit(ctx context.Context, query AuditQuery) ([]AuditRecord, error) {
+	fas.Lock()
+	files, err := fas.rotatedFiles()
+	fas.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, fas.current())
+
+	found := []AuditRecord{}
+	for i := len(files) - 1; i >= 0 && !query.full(found); i-- {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+			// Intentionally Left Blank
+		}
+
+		matched, err := readAuditFile(files[i], query)
+		if err != nil {
+			return nil, err
+		}
+
+		for j := len(matched) - 1; j >= 0 && !query.full(found); j-- {
+			found = append(found, matched[j])
+		}
+	}
+	return found, nil
+}
+
+// readAuditFile finds the records in a single file that are selected by `query`, in the order they were written.
+func readAuditFile(name string, query AuditQuery) (matched []AuditRecord, err error) {
+	handle, err := os.Open(name)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer handle.Close()
+
+	scanner := bufio.NewScanner(handle)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var record AuditRecord
+		if json.Unmarshal(scanner.Bytes(), &record) != nil {
+			continue
+		}
+		if query.Matches(record) {
+			matched = append(matched, record)
+		}
+	}
+	return matched, scanner.Err()
+}
+
+// DefaultAuditStream is the Redis stream that a RedisAuditSink writes to, if one is not specified.
+const DefaultAuditStream = "mirrorcat:audit"
+
+// auditStreamField is the field of each stream entry which holds a record, encoded as JSON.
+const auditStreamField = "record"
+
+// RedisAuditSink adds each record to a Redis stream. If `MaxLen` is greater than zero, the stream is trimmed to
+// approximately that many records.
+type RedisAuditSink struct {
+	redis.UniversalClient
+	Stream string
+	MaxLen int64
+}
+
+func (ras RedisAuditSink) stream() string {
+	if ras.Stream == "" {
+		return DefaultAuditStream
+	}
+	return ras.Stream
+}
+
+// Audit adds `record` to the stream.
+func (ras RedisAuditSink) Audit(ctx context.Context, record AuditRecord) error {
+	encoded, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	return ras.XAdd(&redis.XAddArgs{
+		Stream:       ras.stream(),
+		MaxLenApprox: ras.MaxLen,
+		Values: map[string]interface{}{
+			auditStreamField: string(encoded),
+		},
+	}).Err()
+}
+
+// ReadAudit finds the records selected by `query`, most recent first.
+func (ras RedisAuditSink) ReadAudit(ctx context.Context, query AuditQuery) ([]AuditRecord, error) {
+	const pageSize = 100
+
+	found := []AuditRecord{}
+	end := "+"
+	for !query.full(found) {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+			// Intentionally Left Blank
+		}
+
+		page, err := ras.XRevRangeN(ras.stream(), end, "-", pageSize).Result()
+		if err != nil {
+			return nil, err
+		}
+
+		// Each page after the first begins with the last entry of the page before it.
+		if end != "+" && len(page) > 0 && page[0].ID == end {
+			page = page[1:]
+		}
+		if len(page) == 0 {
+			break
+		}
+
+		for _, entry := range page {
+			encoded, ok := entry.Values[auditStreamField].(string)
+			if !ok {
+				continue
+			}
+
+			var record AuditRecord
+			if json.Unmarshal([]byte(encoded), &record) != nil {
+				continue
+			}
+			if query.Matches(record) && !query.full(found) {
+				found = append(found, record)
+			}
+		}
+		end = page[len(page)-1].ID
+	}
+	return found, nil
+}
@@ -0,0 +1,165 @@
+package mirrorcat_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Azure/mirrorcat"
+	"github.com/go-redis/redis"
+	"github.com/spf13/viper"
+)
+
+// auditRecords creates `count` records, oldest first, alternating between two pushers.
+func auditRecords(count int) []mirrorcat.AuditRecord {
+	pushers := []string{"marstr", "haydenmc"}
+	start := time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	records := make([]mirrorcat.AuditRecord, count)
+	for i := range records {
+		records[i] = mirrorcat.AuditRecord{
+			Time:       start.Add(time.Duration(i) * time.Minute),
+			DeliveryID: string(rune('a' + i)),
+			Pusher:     pushers[i%len(pushers)],
+			Original:   mirrorcat.RemoteRef{Repository: "https://github.com/Azure/mirrorcat.git", Ref: "master"},
+			Mirror:     mirrorcat.RemoteRef{Repository: "https://github.com/marstr/mirrorcat.git", Ref: "master"},
+			NewSHA:     "abc123",
+			Outcome:    mirrorcat.JobSucceeded,
+		}
+	}
+	return records
+}
+
+func TestFileAuditSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mirrorcat_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Each record is a little over 250 bytes, so every file holds two of them.
+	subject, err := mirrorcat.NewFileAuditSink(filepath.Join(dir, "audit"), 600, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	records := auditRecords(7)
+	for _, record := range records {
+		if err = subject.Audit(ctx, record); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The oldest file, holding "a" and "b", was removed when the third file was rotated.
+	files, err := filepath.Glob(filepath.Join(dir, "audit", "*.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 3 {
+		t.Fatalf("got: %d files want: 3 (the current file, and two rotated files)", len(files))
+	}
+
+	// A partially written line shouldn't prevent the rest of the log from being read.
+	current, err := os.OpenFile(filepath.Join(dir, "audit", "audit.jsonl"), os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	current.WriteString(`{"time": "2018-06`)
+	current.Close()
+
+	haydenmc := "haydenmc"
+	testCases := []struct {
+		name  string
+		query mirrorcat.AuditQuery
+		want  []string
+	}{
+		{"all", mirrorcat.AuditQuery{}, []string{"g", "f", "e", "d", "c"}},
+		{"limit", mirrorcat.AuditQuery{Limit: 3}, []string{"g", "f", "e"}},
+		{"pusher", mirrorcat.AuditQuery{Pusher: haydenmc}, []string{"f", "d"}},
+		{"delivery", mirrorcat.AuditQuery{DeliveryID: "c"}, []string{"c"}},
+		{"mirror", mirrorcat.AuditQuery{Repository: "https://github.com/marstr/mirrorcat", Ref: "refs/heads/master"}, []string{"g", "f", "e", "d", "c"}},
+		{"other repository", mirrorcat.AuditQuery{Repository: "https://github.com/haydenmc/mirrorcat"}, []string{}},
+		{"since", mirrorcat.AuditQuery{Since: records[5].Time}, []string{"g", "f"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := subject.ReadAudit(ctx, tc.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(got) != len(tc.want) {
+				t.Fatalf("got: %d records want: %d", len(got), len(tc.want))
+			}
+			for i := range got {
+				if got[i].DeliveryID != tc.want[i] {
+					t.Logf("got: %q want: %q at %d", got[i].DeliveryID, tc.want[i], i)
+					t.Fail()
+				}
+			}
+		})
+	}
+}
+
+func TestRedisAuditSink(t *testing.T) {
+	viper.BindEnv("redis-connection", "MIRRORCAT_REDIS_CONNECTION")
+	viper.SetDefault("redis-connection", "redis://localhost:6379")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	connectionOptions, err := redis.ParseURL(viper.GetString("redis-connection"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := redis.NewClient(connectionOptions)
+	defer client.Close()
+
+	const stream = "mirrorcat:audit:test"
+	if err = client.Del(stream).Err(); err != nil {
+		t.Log("Unable to connect to Redis instance: ", err)
+		t.SkipNow()
+	}
+	defer client.Del(stream)
+
+	subject := mirrorcat.RedisAuditSink{UniversalClient: client, Stream: stream}
+
+	// Enough records are added to be read back in more than one page.
+	records := auditRecords(250)
+	records[42].Outcome = mirrorcat.JobFailed
+	records[42].Error = "exit status 128"
+	for _, record := range records {
+		if err = subject.Audit(ctx, record); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := subject.ReadAudit(ctx, mirrorcat.AuditQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(records) {
+		t.Fatalf("got: %d records want: %d", len(got), len(records))
+	}
+	for i := range got {
+		if want := records[len(records)-1-i]; !got[i].Time.Equal(want.Time) {
+			t.Fatalf("got: %v want: %v at %d", got[i].Time, want.Time, i)
+		}
+	}
+
+	failed := mirrorcat.JobFailed
+	got, err = subject.ReadAudit(ctx, mirrorcat.AuditQuery{Outcome: &failed, Limit: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Error != records[42].Error {
+		t.Logf("got: %v want: only the failed record", got)
+		t.Fail()
+	}
+}
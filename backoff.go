@@ -0,0 +1,30 @@
+package mirrorcat
+
+import "time"
+
+// Backoff computes how long to wait between attempts to reach a dependency which is failing. The first delay is
+// `Min`, and each one after it is twice as long as the last, up to `Max`.
+type Backoff struct {
+	Min time.Duration
+	Max time.Duration
+
+	current time.Duration
+}
+
+// Next fetches the delay to wait before the next attempt.
+func (b *Backoff) Next() time.Duration {
+	switch {
+	case b.current < b.Min:
+		b.current = b.Min
+	case b.current*2 > b.Max:
+		b.current = b.Max
+	default:
+		b.current *= 2
+	}
+	return b.current
+}
+
+// Reset causes the next delay to be `Min` again, after an attempt has succeeded.
+func (b *Backoff) Reset() {
+	b.current = 0
+}
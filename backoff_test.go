@@ -0,0 +1,30 @@
+package mirrorcat_test
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Azure/mirrorcat"
+)
+
+func ExampleBackoff() {
+	subject := mirrorcat.Backoff{
+		Min: time.Second,
+		Max: 5 * time.Second,
+	}
+
+	for i := 0; i < 5; i++ {
+		fmt.Println(subject.Next())
+	}
+
+	subject.Reset()
+	fmt.Println(subject.Next())
+
+	// Output:
+	// 1s
+	// 2s
+	// 4s
+	// 5s
+	// 5s
+	// 1s
+}
@@ -0,0 +1,230 @@
+package mirrorcat
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// CachingFinder decorates a MirrorFinder, remembering the mirrors, exclusions, and options that it finds so that
+// they don't need to be fetched again for each push.
+//
+// Results are remembered for `TTL`. When nothing is found, that is remembered for `NegativeTTL` instead, which may
+// be zero to always ask again. Results are never remembered after the decorated MirrorFinder returns an error.
+// Once `MaxEntries` results are being remembered, the least recently used are forgotten. A `MaxEntries` of zero
+// allows any number of results to be remembered.
+//
+// ListMirrors is passed along to the decorated MirrorFinder without being cached, because each call is expected to
+// be the beginning of an analysis which should see every current mapping.
+type CachingFinder struct {
+	MirrorFinder
+	TTL         time.Duration
+	NegativeTTL time.Duration
+	MaxEntries  int
+
+	sync.Mutex
+	entries map[cacheKey]*list.Element
+	recency *list.List
+}
+
+type cacheKind int
+
+const (
+	cachedMirrors cacheKind = iota
+	cachedExclusions
+	cachedOptions
+)
+
+type cacheKey struct {
+	kind     cacheKind
+	original RemoteRef
+	mirror   RemoteRef
+}
+
+type cacheEntry struct {
+	key     cacheKey
+	refs    []RemoteRef
+	options MirrorOptions
+	expires time.Time
+}
+
+// NewCachingFinder creates a CachingFinder which has not yet remembered any results.
+func NewCachingFinder(inner MirrorFinder, ttl, negativeTTL time.Duration, maxEntries int) *CachingFinder {
+	return &CachingFinder{
+		MirrorFinder: inner,
+		TTL:          ttl,
+		NegativeTTL:  negativeTTL,
+		MaxEntries:   maxEntries,
+		entries:      make(map[cacheKey]*list.Element),
+		recency:      list.New(),
+	}
+}
+
+// FindMirrors publishes the mirrors of `original` that were remembered, or asks the decorated MirrorFinder for them.
+func (cf *CachingFinder) FindMirrors(ctx context.Context, original RemoteRef, results chan<- RemoteRef) error {
+	return cf.findRefs(ctx, cacheKey{kind: cachedMirrors, original: original}, cf.MirrorFinder.FindMirrors, results)
+}
+
+// FindExclusions publishes the exclusions of `original` that were remembered, or asks the decorated MirrorFinder
+// for them if it is an ExclusionFinder.
+func (cf *CachingFinder) FindExclusions(ctx context.Context, original RemoteRef, results chan<- RemoteRef) error {
+	excluder, ok := cf.MirrorFinder.(ExclusionFinder)
+	if !ok {
+		close(results)
+		return nil
+	}
+	return cf.findRefs(ctx, cacheKey{kind: cachedExclusions, original: original}, excluder.FindExclusions, results)
+}
+
+// ListMirrors passes along the mappings of the decorated MirrorFinder, if it is a MirrorLister.
+func (cf *CachingFinder) ListMirrors(ctx context.Context, results chan<- Mapping) error {
+	if lister, ok := cf.MirrorFinder.(MirrorLister); ok {
+		return lister.ListMirrors(ctx, results)
+	}
+	close(results)
+	return nil
+}
+
+// FindOptions fetches the options of a mapping that were remembered, or asks the decorated MirrorFinder for them
+// if it is an OptionsFinder.
+func (cf *CachingFinder) FindOptions(m Mapping) (MirrorOptions, error) {
+	optioner, ok := cf.MirrorFinder.(OptionsFinder)
+	if !ok {
+		return MirrorOptions{}, nil
+	}
+
+	key := cacheKey{kind: cachedOptions, original: m.Original, mirror: m.Mirror}
+	if entry, ok := cf.lookup(key); ok {
+		return entry.options, nil
+	}
+
+	options, err := optioner.FindOptions(m)
+	if err != nil {
+		return options, err
+	}
+
+	ttl := cf.TTL
+	if options == (MirrorOptions{}) {
+		ttl = cf.NegativeTTL
+	}
+	cf.store(cacheEntry{key: key, options: options}, ttl)
+	return options, nil
+}
+
+// Invalidate forgets every result that was remembered about `original`, so that the next request for them is
+// answered by the decorated MirrorFinder.
+func (cf *CachingFinder) Invalidate(original RemoteRef) {
+	cf.Lock()
+	defer cf.Unlock()
+
+	for key, element := range cf.entries {
+		if key.original == original {
+			cf.remove(element)
+		}
+	}
+}
+
+// InvalidateAll forgets every result that was remembered.
+func (cf *CachingFinder) InvalidateAll() {
+	cf.Lock()
+	defer cf.Unlock()
+
+	cf.entries = make(map[cacheKey]*list.Element)
+	cf.recency = list.New()
+}
+
+// Len reports the number of results that are currently remembered, including any which have expired but have not
+// yet been forgotten.
+func (cf *CachingFinder) Len() int {
+	cf.Lock()
+	defer cf.Unlock()
+	return len(cf.entries)
+}
+
+func (cf *CachingFinder) findRefs(ctx context.Context, key cacheKey, find func(context.Context, RemoteRef, chan<- RemoteRef) error, results chan<- RemoteRef) error {
+	if entry, ok := cf.lookup(key); ok {
+		return publishRemoteRefs(ctx, entry.refs, results)
+	}
+
+	defer close(results)
+
+	intermediate := make(chan RemoteRef)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- find(ctx, key.original, intermediate)
+	}()
+
+	var found []RemoteRef
+	for ref := range intermediate {
+		found = append(found, ref)
+		select {
+		case results <- ref:
+			// Intentionally Left Blank
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if err := <-errs; err != nil {
+		return err
+	}
+
+	ttl := cf.TTL
+	if len(found) == 0 {
+		ttl = cf.NegativeTTL
+	}
+	cf.store(cacheEntry{key: key, refs: found}, ttl)
+	return nil
+}
+
+// lookup finds a result which has been remembered, and has not yet expired.
+func (cf *CachingFinder) lookup(key cacheKey) (cacheEntry, bool) {
+	cf.Lock()
+	defer cf.Unlock()
+
+	element, ok := cf.entries[key]
+	if !ok {
+		return cacheEntry{}, false
+	}
+
+	entry := element.Value.(cacheEntry)
+	if !time.Now().Before(entry.expires) {
+		cf.remove(element)
+		return cacheEntry{}, false
+	}
+
+	cf.recency.MoveToFront(element)
+	return entry, true
+}
+
+// store remembers a result for `ttl`, forgetting the least recently used results if there are too many.
+func (cf *CachingFinder) store(entry cacheEntry, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	entry.expires = time.Now().Add(ttl)
+
+	cf.Lock()
+	defer cf.Unlock()
+
+	if cf.entries == nil {
+		cf.entries = make(map[cacheKey]*list.Element)
+		cf.recency = list.New()
+	}
+
+	if existing, ok := cf.entries[entry.key]; ok {
+		cf.remove(existing)
+	}
+	cf.entries[entry.key] = cf.recency.PushFront(entry)
+
+	for cf.MaxEntries > 0 && len(cf.entries) > cf.MaxEntries {
+		cf.remove(cf.recency.Back())
+	}
+}
+
+// remove forgets a single result. The caller must hold the lock.
+func (cf *CachingFinder) remove(element *list.Element) {
+	delete(cf.entries, element.Value.(cacheEntry).key)
+	cf.recency.Remove(element)
+}
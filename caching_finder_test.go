@@ -0,0 +1,140 @@
+package mirrorcat_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Azure/mirrorcat"
+)
+
+// countingFinder is a MirrorFinder which counts how many times it has been asked for mirrors.
+type countingFinder struct {
+	mirrorcat.MirrorFinder
+	calls int32
+}
+
+func (cf *countingFinder) FindMirrors(ctx context.Context, original mirrorcat.RemoteRef, results chan<- mirrorcat.RemoteRef) error {
+	atomic.AddInt32(&cf.calls, 1)
+	return cf.MirrorFinder.FindMirrors(ctx, original, results)
+}
+
+func (cf *countingFinder) Calls() int {
+	return int(atomic.LoadInt32(&cf.calls))
+}
+
+func TestCachingFinder_FindMirrors(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	original := mirrorcat.RemoteRef{Repository: "https://github.com/Azure/mirrorcat", Ref: "master"}
+	mirror := mirrorcat.RemoteRef{Repository: "https://github.com/marstr/mirrorcat", Ref: "master"}
+	lonely := mirrorcat.RemoteRef{Repository: "https://github.com/Azure/mirrorcat", Ref: "dev"}
+
+	inner := mirrorcat.NewDefaultMirrorFinder()
+	inner.AddMirrors(original, mirror)
+	counter := &countingFinder{MirrorFinder: inner}
+
+	subject := mirrorcat.NewCachingFinder(counter, time.Hour, 0, 0)
+
+	for i := 0; i < 3; i++ {
+		got, err := collectMirrors(ctx, subject, original)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 1 || got[0] != "https://github.com/marstr/mirrorcat:master" {
+			t.Logf("got: %v want: %v", got, []string{"https://github.com/marstr/mirrorcat:master"})
+			t.Fail()
+		}
+	}
+
+	if calls := counter.Calls(); calls != 1 {
+		t.Logf("got: %d calls want: 1", calls)
+		t.Fail()
+	}
+
+	// Without negative caching, an original with no mirrors is asked about each time.
+	for i := 0; i < 2; i++ {
+		if _, err := collectMirrors(ctx, subject, lonely); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls := counter.Calls(); calls != 3 {
+		t.Logf("got: %d calls want: 3", calls)
+		t.Fail()
+	}
+
+	subject.Invalidate(original)
+	if _, err := collectMirrors(ctx, subject, original); err != nil {
+		t.Fatal(err)
+	}
+	if calls := counter.Calls(); calls != 4 {
+		t.Logf("got: %d calls after invalidating want: 4", calls)
+		t.Fail()
+	}
+}
+
+func TestCachingFinder_FindMirrors_Expiry(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	original := mirrorcat.RemoteRef{Repository: "https://github.com/Azure/mirrorcat", Ref: "master"}
+	counter := &countingFinder{MirrorFinder: mirrorcat.NewDefaultMirrorFinder()}
+
+	subject := mirrorcat.NewCachingFinder(counter, time.Hour, 20*time.Millisecond, 0)
+
+	for i := 0; i < 2; i++ {
+		if _, err := collectMirrors(ctx, subject, original); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls := counter.Calls(); calls != 1 {
+		t.Logf("got: %d calls want: 1", calls)
+		t.Fail()
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if _, err := collectMirrors(ctx, subject, original); err != nil {
+		t.Fatal(err)
+	}
+	if calls := counter.Calls(); calls != 2 {
+		t.Logf("got: %d calls after expiring want: 2", calls)
+		t.Fail()
+	}
+}
+
+func TestCachingFinder_FindMirrors_MaxEntries(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	subject := mirrorcat.NewCachingFinder(mirrorcat.NewDefaultMirrorFinder(), time.Hour, time.Hour, 2)
+
+	for _, ref := range []string{"a", "b", "c", "a"} {
+		if _, err := collectMirrors(ctx, subject, mirrorcat.RemoteRef{Repository: "repo", Ref: ref}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := subject.Len(); got != 2 {
+		t.Logf("got: %d entries want: 2", got)
+		t.Fail()
+	}
+}
+
+func TestCachingFinder_FindMirrors_DoesNotCacheErrors(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	subject := mirrorcat.NewCachingFinder(failingFinder{err: errors.New("unreachable")}, time.Hour, time.Hour, 0)
+
+	if _, err := collectMirrors(ctx, subject, mirrorcat.RemoteRef{Repository: "repo", Ref: "master"}); err == nil {
+		t.Error("expected the error to be passed along")
+	}
+
+	if got := subject.Len(); got != 0 {
+		t.Logf("got: %d entries want: 0", got)
+		t.Fail()
+	}
+}
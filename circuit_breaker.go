@@ -0,0 +1,285 @@
+package mirrorcat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// BreakerState describes whether a CircuitBreaker is letting requests through to the dependency it protects.
+type BreakerState int
+
+// These are the states that a CircuitBreaker may be in.
+const (
+	// BreakerClosed lets every request through.
+	BreakerClosed BreakerState = iota
+
+	// BreakerOpen refuses every request, because the dependency has failed too many times in a row.
+	BreakerOpen
+
+	// BreakerHalfOpen lets a single request through, to learn whether the dependency has recovered.
+	BreakerHalfOpen
+)
+
+func (state BreakerState) String() string {
+	switch state {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("BreakerState(%d)", int(state))
+	}
+}
+
+// MarshalText allows a BreakerState to be reported by name.
+func (state BreakerState) MarshalText() ([]byte, error) {
+	return []byte(state.String()), nil
+}
+
+// CircuitBreaker keeps track of the consecutive failures of a dependency. Once `Threshold` failures in a row have
+// been reported, the breaker opens and refuses requests until `Cooldown` has passed. It then becomes half-open, and
+// lets a single request through. If that request succeeds the breaker closes, otherwise it opens again.
+type CircuitBreaker struct {
+	Threshold int
+	Cooldown  time.Duration
+
+	sync.Mutex
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// NewCircuitBreaker creates a CircuitBreaker which is closed.
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		Threshold: threshold,
+		Cooldown:  cooldown,
+	}
+}
+
+// Allow reports whether a request should be sent to the dependency. Each request that is allowed should be followed
+// by a call to either `Succeeded` or `Failed`.
+func (cb *CircuitBreaker) Allow() bool {
+	cb.Lock()
+	defer cb.Unlock()
+
+	switch cb.state() {
+	case BreakerClosed:
+		return true
+	case BreakerHalfOpen:
+		if cb.probing {
+			return false
+		}
+		cb.probing = true
+		return true
+	default:
+		return false
+	}
+}
+
+// Succeeded closes the breaker.
+func (cb *CircuitBreaker) Succeeded() {
+	cb.Lock()
+	defer cb.Unlock()
+
+	cb.failures = 0
+	cb.openedAt = time.Time{}
+	cb.probing = false
+}
+
+// Failed records a failure of the dependency, opening the breaker if there have been too many in a row.
+func (cb *CircuitBreaker) Failed() {
+	cb.Lock()
+	defer cb.Unlock()
+
+	cb.failures++
+	if cb.failures >= cb.Threshold || !cb.openedAt.IsZero() {
+		cb.openedAt = time.Now()
+	}
+	cb.probing = false
+}
+
+// abandoned allows another request through a half-open breaker, when the caller of the previous one stopped waiting
+// before it finished.
+func (cb *CircuitBreaker) abandoned() {
+	cb.Lock()
+	defer cb.Unlock()
+	cb.probing = false
+}
+
+// Trip opens the breaker, regardless of how many failures have been reported.
+func (cb *CircuitBreaker) Trip() {
+	cb.Lock()
+	defer cb.Unlock()
+
+	if cb.failures < cb.Threshold {
+		cb.failures = cb.Threshold
+	}
+	cb.openedAt = time.Now()
+	cb.probing = false
+}
+
+// State reports whether the breaker is currently letting requests through.
+func (cb *CircuitBreaker) State() BreakerState {
+	cb.Lock()
+	defer cb.Unlock()
+	return cb.state()
+}
+
+func (cb *CircuitBreaker) state() BreakerState {
+	switch {
+	case cb.openedAt.IsZero():
+		return BreakerClosed
+	case time.Since(cb.openedAt) >= cb.Cooldown:
+		return BreakerHalfOpen
+	default:
+		return BreakerOpen
+	}
+}
+
+// ErrBreakerOpen is returned when a request is refused by a CircuitBreaker, and there is nothing else that can
+// safely be returned in its place.
+var ErrBreakerOpen = errors.New("circuit breaker is open")
+
+// BreakerFinder decorates a MirrorFinder with a CircuitBreaker. While the breaker is refusing requests, no mirrors
+// or options are found, rather than returning an error. This allows a MergeFinder to continue consulting its other
+// children while a dependency of this one is unavailable.
+//
+// Exclusions are treated differently, because finding none would allow mirrors found by other children to be pushed
+// to even though they were excluded. While the breaker is refusing requests, the exclusions most recently found for
+// an original are remembered by `Exclusions` and used in their place. If there are none to use, ErrBreakerOpen is
+// returned.
+type BreakerFinder struct {
+	MirrorFinder
+	Breaker    *CircuitBreaker
+	Exclusions *ExclusionMemory
+}
+
+// ExclusionMemory holds the exclusions a BreakerFinder most recently found for each original. The zero value is
+// ready to use, and holds nothing.
+type ExclusionMemory struct {
+	sync.Mutex
+	found map[RemoteRef][]RemoteRef
+}
+
+func (em *ExclusionMemory) remember(original RemoteRef, exclusions []RemoteRef) {
+	if em == nil {
+		return
+	}
+
+	em.Lock()
+	defer em.Unlock()
+
+	if em.found == nil {
+		em.found = make(map[RemoteRef][]RemoteRef)
+	}
+	em.found[original] = exclusions
+}
+
+func (em *ExclusionMemory) recall(original RemoteRef) (exclusions []RemoteRef, ok bool) {
+	if em == nil {
+		return
+	}
+
+	em.Lock()
+	defer em.Unlock()
+	exclusions, ok = em.found[original]
+	return
+}
+
+// FindMirrors passes along the mirrors found by the decorated MirrorFinder, if the breaker allows it.
+func (bf BreakerFinder) FindMirrors(ctx context.Context, original RemoteRef, results chan<- RemoteRef) error {
+	if !bf.Breaker.Allow() {
+		close(results)
+		return nil
+	}
+	return bf.record(ctx, bf.MirrorFinder.FindMirrors(ctx, original, results))
+}
+
+// FindExclusions passes along the exclusions of the decorated MirrorFinder, if it is an ExclusionFinder and the
+// breaker allows it. Otherwise, the exclusions that were remembered for `original` are used.
+func (bf BreakerFinder) FindExclusions(ctx context.Context, original RemoteRef, results chan<- RemoteRef) error {
+	excluder, ok := bf.MirrorFinder.(ExclusionFinder)
+	if !ok {
+		close(results)
+		return nil
+	}
+
+	if !bf.Breaker.Allow() {
+		remembered, ok := bf.Exclusions.recall(original)
+		if !ok {
+			close(results)
+			return ErrBreakerOpen
+		}
+		return publishRemoteRefs(ctx, remembered, results)
+	}
+
+	defer close(results)
+
+	intermediate := make(chan RemoteRef)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- excluder.FindExclusions(ctx, original, intermediate)
+	}()
+
+	var found []RemoteRef
+	for ref := range intermediate {
+		found = append(found, ref)
+		select {
+		case results <- ref:
+			// Intentionally Left Blank
+		case <-ctx.Done():
+			for range intermediate {
+			}
+			return bf.record(ctx, ctx.Err())
+		}
+	}
+
+	if err := bf.record(ctx, <-errs); err != nil {
+		return err
+	}
+	bf.Exclusions.remember(original, found)
+	return nil
+}
+
+// ListMirrors passes along the mappings of the decorated MirrorFinder, if it is a MirrorLister and the breaker
+// allows it.
+func (bf BreakerFinder) ListMirrors(ctx context.Context, results chan<- Mapping) error {
+	lister, ok := bf.MirrorFinder.(MirrorLister)
+	if !ok || !bf.Breaker.Allow() {
+		close(results)
+		return nil
+	}
+	return bf.record(ctx, lister.ListMirrors(ctx, results))
+}
+
+// FindOptions passes along the options found by the decorated MirrorFinder, if it is an OptionsFinder and the
+// breaker allows it. Otherwise, the zero value of MirrorOptions is returned.
+func (bf BreakerFinder) FindOptions(m Mapping) (MirrorOptions, error) {
+	optioner, ok := bf.MirrorFinder.(OptionsFinder)
+	if !ok || !bf.Breaker.Allow() {
+		return MirrorOptions{}, nil
+	}
+
+	options, err := optioner.FindOptions(m)
+	return options, bf.record(context.Background(), err)
+}
+
+// record reports the outcome of a request to the breaker. Requests that were abandoned by their caller say nothing
+// about the health of the dependency, so they are not counted as failures.
+func (bf BreakerFinder) record(ctx context.Context, err error) error {
+	switch {
+	case err == nil:
+		bf.Breaker.Succeeded()
+	case ctx.Err() != nil:
+		bf.Breaker.abandoned()
+	default:
+		bf.Breaker.Failed()
+	}
+	return err
+}
@@ -0,0 +1,161 @@
+package mirrorcat_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Azure/mirrorcat"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	subject := mirrorcat.NewCircuitBreaker(2, 50*time.Millisecond)
+
+	expectState := func(want mirrorcat.BreakerState) {
+		t.Helper()
+		if got := subject.State(); got != want {
+			t.Logf("got: %v want: %v", got, want)
+			t.Fail()
+		}
+	}
+
+	expectState(mirrorcat.BreakerClosed)
+
+	subject.Failed()
+	expectState(mirrorcat.BreakerClosed)
+
+	subject.Failed()
+	expectState(mirrorcat.BreakerOpen)
+	if subject.Allow() {
+		t.Error("expected an open breaker to refuse requests")
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	expectState(mirrorcat.BreakerHalfOpen)
+	if !subject.Allow() {
+		t.Error("expected a half-open breaker to allow a single request")
+	}
+	if subject.Allow() {
+		t.Error("expected a half-open breaker to refuse a second request")
+	}
+
+	subject.Failed()
+	expectState(mirrorcat.BreakerOpen)
+
+	subject.Succeeded()
+	expectState(mirrorcat.BreakerClosed)
+
+	subject.Trip()
+	expectState(mirrorcat.BreakerOpen)
+}
+
+func TestBreakerFinder_FindMirrors(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	original := mirrorcat.RemoteRef{Repository: "https://github.com/Azure/mirrorcat", Ref: "master"}
+	mirror := mirrorcat.RemoteRef{Repository: "https://github.com/marstr/mirrorcat", Ref: "master"}
+
+	static := mirrorcat.NewDefaultMirrorFinder()
+	static.AddMirrors(original, mirror)
+
+	breaker := mirrorcat.NewCircuitBreaker(1, time.Hour)
+	subject := mirrorcat.MergeFinder{
+		mirrorcat.BreakerFinder{MirrorFinder: failingFinder{err: errors.New("unreachable")}, Breaker: breaker},
+		static,
+	}
+
+	find := func() ([]mirrorcat.RemoteRef, error) {
+		results := make(chan mirrorcat.RemoteRef)
+		errs := make(chan error, 1)
+		go func() {
+			errs <- subject.FindMirrors(ctx, original, results)
+		}()
+
+		var found []mirrorcat.RemoteRef
+		for result := range results {
+			found = append(found, result)
+		}
+		return found, <-errs
+	}
+
+	if _, err := find(); err == nil {
+		t.Error("expected the failure to be reported while the breaker is closed")
+	}
+
+	if got := breaker.State(); got != mirrorcat.BreakerOpen {
+		t.Logf("got: %v want: %v", got, mirrorcat.BreakerOpen)
+		t.Fail()
+	}
+
+	found, err := find()
+	if err != nil {
+		t.Error(err)
+	}
+	if len(found) != 1 || found[0] != mirror {
+		t.Logf("got: %v want: %v", found, []mirrorcat.RemoteRef{mirror})
+		t.Fail()
+	}
+}
+
+func TestBreakerFinder_FindExclusions(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	excluding := mirrorcat.RemoteRef{Repository: "https://github.com/Azure/mirrorcat", Ref: "master"}
+	excluded := mirrorcat.RemoteRef{Repository: "https://github.com/marstr/mirrorcat", Ref: "master"}
+	nothingExcluded := mirrorcat.RemoteRef{Repository: "https://github.com/Azure/mirrorcat", Ref: "dev"}
+	neverAsked := mirrorcat.RemoteRef{Repository: "https://github.com/Azure/mirrorcat", Ref: "release"}
+
+	dependency := mirrorcat.NewDefaultMirrorFinder()
+	dependency.AddExclusions(excluding, excluded)
+
+	breaker := mirrorcat.NewCircuitBreaker(1, time.Hour)
+	subject := mirrorcat.BreakerFinder{MirrorFinder: dependency, Breaker: breaker, Exclusions: &mirrorcat.ExclusionMemory{}}
+
+	find := func(original mirrorcat.RemoteRef) ([]mirrorcat.RemoteRef, error) {
+		results := make(chan mirrorcat.RemoteRef)
+		errs := make(chan error, 1)
+		go func() {
+			errs <- subject.FindExclusions(ctx, original, results)
+		}()
+
+		var found []mirrorcat.RemoteRef
+		for result := range results {
+			found = append(found, result)
+		}
+		return found, <-errs
+	}
+
+	for _, original := range []mirrorcat.RemoteRef{excluding, nothingExcluded} {
+		if _, err := find(original); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	breaker.Trip()
+
+	found, err := find(excluding)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(found) != 1 || found[0] != excluded {
+		t.Logf("got: %v want: %v", found, []mirrorcat.RemoteRef{excluded})
+		t.Fail()
+	}
+
+	found, err = find(nothingExcluded)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(found) != 0 {
+		t.Logf("got: %v want: none", found)
+		t.Fail()
+	}
+
+	if _, err = find(neverAsked); err != mirrorcat.ErrBreakerOpen {
+		t.Logf("got: %v want: %v", err, mirrorcat.ErrBreakerOpen)
+		t.Fail()
+	}
+}
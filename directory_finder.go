@@ -0,0 +1,271 @@
+package mirrorcat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"gopkg.in/yaml.v2"
+)
+
+// DirectoryFinder finds mirrors in each YAML or JSON file directly inside of a directory, so that separate teams
+// may each own a file of mappings. Each file is shaped like a MirrorCat config file, with `mirrors` and
+// `exclusions` properties:
+//
+//	mirrors:
+//	  https://github.com/Azure/mirrorcat.git:
+//	    master:
+//	      https://github.com/marstr/mirrorcat.git:
+//	        - master
+//
+// Files are read in order of their names. If the same mapping appears in more than one file, it is attributed to
+// the first of them.
+type DirectoryFinder struct {
+	Dir string
+
+	// OnLoad is called each time that `Watch` reads the directory again, with any errors that were encountered.
+	OnLoad func([]error)
+
+	sync.RWMutex
+	files map[string]mappingFile
+}
+
+// MappingFileError describes why a file read by a DirectoryFinder could not be used.
+type MappingFileError struct {
+	Path string
+	Err  error
+}
+
+func (err MappingFileError) Error() string {
+	return fmt.Sprintf("%s: %v", err.Path, err.Err)
+}
+
+// mappingFile holds the contents of a single file read by a DirectoryFinder.
+type mappingFile struct {
+	Mirrors    map[string]map[string]map[string][]string `yaml:"mirrors" json:"mirrors"`
+	Exclusions map[string]map[string]map[string][]string `yaml:"exclusions" json:"exclusions"`
+}
+
+// directoryExtensions lists the extensions of the files that a DirectoryFinder reads.
+var directoryExtensions = map[string]struct{}{
+	".yml":  {},
+	".yaml": {},
+	".json": {},
+}
+
+// NewDirectoryFinder creates a DirectoryFinder for `dir`, which has not yet been read.
+func NewDirectoryFinder(dir string) *DirectoryFinder {
+	return &DirectoryFinder{
+		Dir:   dir,
+		files: make(map[string]mappingFile),
+	}
+}
+
+// Load reads every file in the directory, replacing the mappings that were previously read. A file which can't be
+// read or parsed keeps the mappings that were previously read from it, and is reported as a MappingFileError.
+func (df *DirectoryFinder) Load() (errs []error) {
+	entries, err := ioutil.ReadDir(df.Dir)
+	if err != nil {
+		return []error{err}
+	}
+
+	df.RLock()
+	previous := df.files
+	df.RUnlock()
+
+	updated := make(map[string]mappingFile, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if _, ok := directoryExtensions[strings.ToLower(filepath.Ext(name))]; !ok || entry.IsDir() || strings.HasPrefix(name, ".") {
+			continue
+		}
+
+		path := filepath.Join(df.Dir, name)
+		contents, err := readMappingFile(path)
+		if err != nil {
+			errs = append(errs, MappingFileError{Path: path, Err: err})
+			if last, ok := previous[path]; ok {
+				updated[path] = last
+			}
+			continue
+		}
+		updated[path] = contents
+	}
+
+	df.Lock()
+	defer df.Unlock()
+	df.files = updated
+	return
+}
+
+// Watch reads the directory, then reads it again each time that a file in it changes, until `ctx` is cancelled.
+// Changes which happen in quick succession, as when a directory is replaced during a deployment, are read together.
+func (df *DirectoryFinder) Watch(ctx context.Context) error {
+	const settleTime = 100 * time.Millisecond
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	if err = watcher.Add(df.Dir); err != nil {
+		return err
+	}
+
+	df.reload()
+
+	var settled <-chan time.Time
+	for {
+		select {
+		case <-watcher.Events:
+			settled = time.After(settleTime)
+		case <-settled:
+			settled = nil
+			df.reload()
+		case err = <-watcher.Errors:
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (df *DirectoryFinder) reload() {
+	errs := df.Load()
+	if df.OnLoad != nil {
+		df.OnLoad(errs)
+	}
+}
+
+// FindMirrors publishes each mirror of `original` found in any file.
+func (df *DirectoryFinder) FindMirrors(ctx context.Context, original RemoteRef, results chan<- RemoteRef) error {
+	return publishRemoteRefs(ctx, df.targetsOf(original, false), results)
+}
+
+// FindExclusions publishes each exclusion of `original` found in any file.
+func (df *DirectoryFinder) FindExclusions(ctx context.Context, original RemoteRef, results chan<- RemoteRef) error {
+	return publishRemoteRefs(ctx, df.targetsOf(original, true), results)
+}
+
+// ListMirrors publishes every mapping found in any file, ordered by the file that it was found in.
+func (df *DirectoryFinder) ListMirrors(ctx context.Context, results chan<- Mapping) error {
+	return publishMappings(ctx, df.mappings(false), results)
+}
+
+// ListExclusions publishes every exclusion found in any file, ordered by the file that it was found in.
+func (df *DirectoryFinder) ListExclusions(ctx context.Context, results chan<- Mapping) error {
+	return publishMappings(ctx, df.mappings(true), results)
+}
+
+// SourceOf finds the file that a mirror, or exclusion, was read from.
+func (df *DirectoryFinder) SourceOf(m Mapping) (path string, ok bool) {
+	df.RLock()
+	defer df.RUnlock()
+
+	for _, path := range df.paths() {
+		file := df.files[path]
+		for _, exclusion := range []bool{false, true} {
+			for _, found := range file.mappings(exclusion) {
+				if found == m {
+					return path, true
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+func (df *DirectoryFinder) targetsOf(original RemoteRef, exclusion bool) (targets []RemoteRef) {
+	for _, m := range df.mappings(exclusion) {
+		if m.Original == original {
+			targets = append(targets, m.Mirror)
+		}
+	}
+	return
+}
+
+// mappings gathers the mirrors, or exclusions, found in every file, without duplicates.
+func (df *DirectoryFinder) mappings(exclusion bool) (mappings []Mapping) {
+	df.RLock()
+	defer df.RUnlock()
+
+	seen := make(map[Mapping]struct{})
+	for _, path := range df.paths() {
+		for _, m := range df.files[path].mappings(exclusion) {
+			if _, ok := seen[m]; ok {
+				continue
+			}
+			seen[m] = struct{}{}
+			mappings = append(mappings, m)
+		}
+	}
+	return
+}
+
+// paths lists each file that has been read, in order of their names. The caller must hold the lock.
+func (df *DirectoryFinder) paths() []string {
+	paths := make([]string, 0, len(df.files))
+	for path := range df.files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// mappings flattens the mirrors, or exclusions, of a single file, ordered by original then mirror.
+func (file mappingFile) mappings(exclusion bool) (mappings []Mapping) {
+	tree := file.Mirrors
+	if exclusion {
+		tree = file.Exclusions
+	}
+
+	for origRepo, origRefs := range tree {
+		for origRef, remoteRepos := range origRefs {
+			for remote, remoteRefs := range remoteRepos {
+				for _, ref := range remoteRefs {
+					mappings = append(mappings, Mapping{
+						Original: RemoteRef{Repository: origRepo, Ref: origRef},
+						Mirror:   RemoteRef{Repository: remote, Ref: ref},
+					})
+				}
+			}
+		}
+	}
+
+	sortMappings(mappings)
+	return
+}
+
+// readMappingFile parses a single file read by a DirectoryFinder, as JSON or YAML depending on its extension.
+func readMappingFile(path string) (contents mappingFile, err error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		err = json.Unmarshal(raw, &contents)
+	} else {
+		err = yaml.UnmarshalStrict(raw, &contents)
+	}
+	if err != nil {
+		return
+	}
+
+	for _, m := range append(contents.mappings(false), contents.mappings(true)...) {
+		for _, target := range []RemoteRef{m.Original, m.Mirror} {
+			if err = target.Validate(); err != nil {
+				return mappingFile{}, err
+			}
+		}
+	}
+	return
+}
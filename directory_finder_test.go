@@ -0,0 +1,188 @@
+package mirrorcat_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Azure/mirrorcat"
+)
+
+func writeMappingFile(t *testing.T, path, contents string) {
+	t.Helper()
+	if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDirectoryFinder_Load(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	dir, err := ioutil.TempDir("", "mirrorcat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeMappingFile(t, filepath.Join(dir, "team-a.yml"), `
+mirrors:
+  https://github.com/Azure/mirrorcat.git:
+    master:
+      https://github.com/marstr/mirrorcat.git:
+        - master
+exclusions:
+  https://github.com/Azure/mirrorcat.git:
+    master:
+      https://github.com/haydenmc/mirrorcat.git:
+        - master
+`)
+	writeMappingFile(t, filepath.Join(dir, "team-b.json"), `{
+  "mirrors": {
+    "https://github.com/Azure/mirrorcat.git": {
+      "master": {
+        "https://github.com/haydenmc/mirrorcat.git": ["dev"]
+      }
+    }
+  }
+}`)
+	writeMappingFile(t, filepath.Join(dir, "broken.yml"), "mirrors: [not, a, map]")
+	writeMappingFile(t, filepath.Join(dir, "README.md"), "Not a mapping file.")
+
+	subject := mirrorcat.NewDirectoryFinder(dir)
+
+	errs := subject.Load()
+	if len(errs) != 1 {
+		t.Fatalf("got: %v want: a single error", errs)
+	}
+	if fileErr, ok := errs[0].(mirrorcat.MappingFileError); !ok || fileErr.Path != filepath.Join(dir, "broken.yml") {
+		t.Logf("got: %#v want: an error attributed to broken.yml", errs[0])
+		t.Fail()
+	}
+
+	original := mirrorcat.RemoteRef{Repository: "https://github.com/Azure/mirrorcat.git", Ref: "master"}
+	got, err := collectMirrors(ctx, subject, original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"https://github.com/haydenmc/mirrorcat.git:dev", "https://github.com/marstr/mirrorcat.git:master"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Logf("got: %v want: %v", got, want)
+		t.Fail()
+	}
+
+	source, ok := subject.SourceOf(mirrorcat.Mapping{
+		Original: original,
+		Mirror:   mirrorcat.RemoteRef{Repository: "https://github.com/haydenmc/mirrorcat.git", Ref: "dev"},
+	})
+	if !ok || source != filepath.Join(dir, "team-b.json") {
+		t.Logf("got: %q (found: %v) want: %q", source, ok, filepath.Join(dir, "team-b.json"))
+		t.Fail()
+	}
+
+	exclusions := make(chan mirrorcat.RemoteRef)
+	go subject.FindExclusions(ctx, original, exclusions)
+	var excluded []mirrorcat.RemoteRef
+	for exclusion := range exclusions {
+		excluded = append(excluded, exclusion)
+	}
+	if len(excluded) != 1 || excluded[0].Repository != "https://github.com/haydenmc/mirrorcat.git" {
+		t.Logf("got: %v want: a single exclusion", excluded)
+		t.Fail()
+	}
+}
+
+func TestDirectoryFinder_Load_KeepsLastGoodContents(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	dir, err := ioutil.TempDir("", "mirrorcat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "team.yml")
+	writeMappingFile(t, path, `
+mirrors:
+  https://github.com/Azure/mirrorcat.git:
+    master:
+      https://github.com/marstr/mirrorcat.git:
+        - master
+`)
+
+	subject := mirrorcat.NewDirectoryFinder(dir)
+	if errs := subject.Load(); len(errs) != 0 {
+		t.Fatal(errs)
+	}
+
+	writeMappingFile(t, path, "mirrors: {")
+	if errs := subject.Load(); len(errs) != 1 {
+		t.Fatalf("got: %v want: a single error", errs)
+	}
+
+	mappings, err := mirrorcat.CollectMappings(ctx, subject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mappings) != 1 {
+		t.Logf("got: %v want: the mapping read before the file was broken", mappings)
+		t.Fail()
+	}
+}
+
+func TestDirectoryFinder_Watch(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	dir, err := ioutil.TempDir("", "mirrorcat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	loads := make(chan []error, 2)
+	subject := mirrorcat.NewDirectoryFinder(dir)
+	subject.OnLoad = func(errs []error) { loads <- errs }
+
+	watchErrs := make(chan error, 1)
+	go func() {
+		watchErrs <- subject.Watch(ctx)
+	}()
+
+	select {
+	case <-loads:
+		// Intentionally Left Blank
+	case err := <-watchErrs:
+		t.Fatal(err)
+	}
+
+	writeMappingFile(t, filepath.Join(dir, "team.yml"), `
+mirrors:
+  https://github.com/Azure/mirrorcat.git:
+    master:
+      https://github.com/marstr/mirrorcat.git:
+        - master
+`)
+
+	select {
+	case errs := <-loads:
+		if len(errs) != 0 {
+			t.Fatal(errs)
+		}
+	case <-ctx.Done():
+		t.Fatal(ctx.Err())
+	}
+
+	got, err := collectMirrors(ctx, subject, mirrorcat.RemoteRef{Repository: "https://github.com/Azure/mirrorcat.git", Ref: "master"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0] != "https://github.com/marstr/mirrorcat.git:master" {
+		t.Logf("got: %v want: %v", got, []string{"https://github.com/marstr/mirrorcat.git:master"})
+		t.Fail()
+	}
+}
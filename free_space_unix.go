@@ -0,0 +1,15 @@
+//go:build !windows
+// +build !windows
+
+package mirrorcat
+
+import "syscall"
+
+// FreeSpace finds the number of bytes available to unprivileged users on the file system holding `dir`.
+func FreeSpace(dir string) (uint64, error) {
+	var stats syscall.Statfs_t
+	if err := syscall.Statfs(dir, &stats); err != nil {
+		return 0, err
+	}
+	return uint64(stats.Bavail) * uint64(stats.Bsize), nil
+}
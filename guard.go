@@ -0,0 +1,213 @@
+package mirrorcat
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Reasons that a Mapping may be rejected.
+const (
+	RejectedSelfMirror = "self-mirror"
+	RejectedCycle      = "cycle"
+)
+
+// Rejection records a Mapping that MirrorCat has refused to act upon, and why.
+type Rejection struct {
+	Mapping
+	Reason string    `json:"reason"`
+	Time   time.Time `json:"time"`
+}
+
+// CollectMappings drains every mapping that a MirrorLister knows about into a slice.
+func CollectMappings(ctx context.Context, lister MirrorLister) (mappings []Mapping, err error) {
+	results := make(chan Mapping)
+	errs := make(chan error, 1)
+
+	go func() {
+		errs <- lister.ListMirrors(ctx, results)
+	}()
+
+	for mapping := range results {
+		mappings = append(mappings, mapping)
+	}
+	err = <-errs
+	return
+}
+
+// GuardedFinder decorates a MirrorFinder, withholding any mirror that would cause a ref to be
+// pushed onto itself, or which has been rejected by an analysis of every known mapping.
+type GuardedFinder struct {
+	MirrorFinder
+	sync.RWMutex
+	rejected map[Mapping]Rejection
+}
+
+// NewGuardedFinder creates a GuardedFinder which has not yet rejected any mappings.
+func NewGuardedFinder(inner MirrorFinder) *GuardedFinder {
+	return &GuardedFinder{
+		MirrorFinder: inner,
+		rejected:     make(map[Mapping]Rejection),
+	}
+}
+
+// Analyze enumerates each mapping of the decorated MirrorFinder, and rejects all of those which are either
+// self-mirrors or participate in a cycle. Rejections from any previous analysis are forgotten.
+//
+// If the decorated MirrorFinder is not a MirrorLister, no mappings are rejected.
+func (guard *GuardedFinder) Analyze(ctx context.Context) (rejected []Rejection, err error) {
+	if lister, ok := guard.MirrorFinder.(MirrorLister); ok {
+		var mappings []Mapping
+		mappings, err = CollectMappings(ctx, lister)
+		if err != nil {
+			return
+		}
+		_, rejected = RejectMappings(mappings)
+	}
+
+	updated := make(map[Mapping]Rejection, len(rejected))
+	for _, r := range rejected {
+		updated[r.canonical()] = r
+	}
+
+	guard.Lock()
+	defer guard.Unlock()
+	guard.rejected = updated
+	return
+}
+
+// Rejections lists each mapping that is currently being withheld.
+func (guard *GuardedFinder) Rejections() []Rejection {
+	guard.RLock()
+	defer guard.RUnlock()
+
+	retval := make([]Rejection, 0, len(guard.rejected))
+	for _, r := range guard.rejected {
+		retval = append(retval, r)
+	}
+	return retval
+}
+
+// Allows determines whether or not a mapping is safe to act upon, given the most recent analysis.
+func (guard *GuardedFinder) Allows(m Mapping) bool {
+	if m.IsSelfMirror() {
+		return false
+	}
+
+	guard.RLock()
+	defer guard.RUnlock()
+	_, rejected := guard.rejected[m.canonical()]
+	return !rejected
+}
+
+// FindMirrors publishes each mirror found by the decorated MirrorFinder, skipping those that have been rejected.
+func (guard *GuardedFinder) FindMirrors(ctx context.Context, original RemoteRef, results chan<- RemoteRef) error {
+	defer close(results)
+
+	intermediate := make(chan RemoteRef)
+	errs := make(chan error, 1)
+
+	go func() {
+		errs <- guard.MirrorFinder.FindMirrors(ctx, original, intermediate)
+	}()
+
+	for mirror := range intermediate {
+		candidate := Mapping{Original: original, Mirror: mirror}
+		if candidate.IsSelfMirror() {
+			guard.reject(Rejection{Mapping: candidate, Reason: RejectedSelfMirror, Time: time.Now()})
+			continue
+		}
+
+		if !guard.Allows(candidate) {
+			continue
+		}
+
+		select {
+		case results <- mirror:
+			// Intentionally Left Blank
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return <-errs
+}
+
+func (guard *GuardedFinder) reject(r Rejection) {
+	guard.Lock()
+	defer guard.Unlock()
+	guard.rejected[r.canonical()] = r
+}
+
+func (m Mapping) canonical() Mapping {
+	return Mapping{
+		Original: m.Original.Canonical(),
+		Mirror:   m.Mirror.Canonical(),
+	}
+}
+
+// PushHistory remembers which commits have recently been pushed along which mappings. This allows
+// MirrorCat to recognize a webhook that was only sent because MirrorCat itself pushed a commit, and
+// avoid pushing that commit right back to where it came from.
+type PushHistory struct {
+	sync.Mutex
+	ttl     time.Duration
+	entries map[pushRecord]time.Time
+}
+
+type pushRecord struct {
+	Mapping
+	commit string
+}
+
+// NewPushHistory creates an empty PushHistory which remembers each push for `ttl`.
+func NewPushHistory(ttl time.Duration) *PushHistory {
+	return &PushHistory{
+		ttl:     ttl,
+		entries: make(map[pushRecord]time.Time),
+	}
+}
+
+// Record notes that `commit` was just pushed from `m.Original` to `m.Mirror`.
+func (ph *PushHistory) Record(m Mapping, commit string) {
+	if commit == "" {
+		return
+	}
+
+	ph.Lock()
+	defer ph.Unlock()
+
+	now := time.Now()
+	for record, expiry := range ph.entries {
+		if now.After(expiry) {
+			delete(ph.entries, record)
+		}
+	}
+
+	ph.entries[pushRecord{Mapping: m.canonical(), commit: commit}] = now.Add(ph.ttl)
+}
+
+// CheckLoop returns a non-nil error if pushing `commit` along `m` would only send it back
+// to the repository that it was just pushed from.
+func (ph *PushHistory) CheckLoop(m Mapping, commit string) error {
+	if commit == "" {
+		return nil
+	}
+
+	ph.Lock()
+	defer ph.Unlock()
+
+	reversed := pushRecord{
+		Mapping: Mapping{
+			Original: m.Mirror.Canonical(),
+			Mirror:   m.Original.Canonical(),
+		},
+		commit: commit,
+	}
+
+	if expiry, ok := ph.entries[reversed]; ok && time.Now().Before(expiry) {
+		return fmt.Errorf("commit %s just arrived at %s (%s) from %s (%s), refusing to push it back", commit, m.Original.Repository, m.Original.Ref, m.Mirror.Repository, m.Mirror.Ref)
+	}
+	return nil
+}
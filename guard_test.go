@@ -0,0 +1,112 @@
+package mirrorcat_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Azure/mirrorcat"
+)
+
+func TestGuardedFinder_FindMirrors(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	a := mirrorcat.RemoteRef{Repository: "https://github.com/Azure/mirrorcat", Ref: "master"}
+	b := mirrorcat.RemoteRef{Repository: "https://github.com/marstr/mirrorcat", Ref: "master"}
+	c := mirrorcat.RemoteRef{Repository: "https://github.com/haydenmc/mirrorcat", Ref: "master"}
+
+	inner := mirrorcat.NewDefaultMirrorFinder()
+	inner.AddMirrors(a, a, b, c)
+	inner.AddMirrors(b, a)
+
+	subject := mirrorcat.NewGuardedFinder(inner)
+
+	rejected, err := subject.Analyze(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rejected) != 3 {
+		t.Logf("got: %d rejections want: 3\n%v", len(rejected), rejected)
+		t.Fail()
+	}
+
+	results := make(chan mirrorcat.RemoteRef)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- subject.FindMirrors(ctx, a, results)
+	}()
+
+	var seen []mirrorcat.RemoteRef
+	for result := range results {
+		seen = append(seen, result)
+	}
+
+	if err = <-errs; err != nil {
+		t.Error(err)
+	}
+
+	if len(seen) != 1 || seen[0] != c {
+		t.Logf("got: %v want: %v", seen, []mirrorcat.RemoteRef{c})
+		t.Fail()
+	}
+}
+
+func TestGuardedFinder_FindMirrors_RejectsSelfMirrorsWithoutAnalysis(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	a := mirrorcat.RemoteRef{Repository: "https://github.com/Azure/mirrorcat", Ref: "master"}
+
+	inner := mirrorcat.NewDefaultMirrorFinder()
+	inner.AddMirrors(a, mirrorcat.RemoteRef{Repository: a.Repository + ".git", Ref: "refs/heads/master"})
+
+	subject := mirrorcat.NewGuardedFinder(inner)
+
+	results := make(chan mirrorcat.RemoteRef)
+	go subject.FindMirrors(ctx, a, results)
+
+	for result := range results {
+		t.Log("unexpected result: ", result)
+		t.Fail()
+	}
+
+	if got := len(subject.Rejections()); got != 1 {
+		t.Logf("got: %d rejections want: 1", got)
+		t.Fail()
+	}
+}
+
+func TestPushHistory_CheckLoop(t *testing.T) {
+	const commit = "0d1a26e67d8f5eaf1f6ba5c57fc3c7d91ac0fd1c"
+
+	a := mirrorcat.RemoteRef{Repository: "https://github.com/Azure/mirrorcat", Ref: "master"}
+	b := mirrorcat.RemoteRef{Repository: "https://github.com/marstr/mirrorcat", Ref: "master"}
+	c := mirrorcat.RemoteRef{Repository: "https://github.com/haydenmc/mirrorcat", Ref: "master"}
+
+	subject := mirrorcat.NewPushHistory(time.Minute)
+	subject.Record(mirrorcat.Mapping{Original: a, Mirror: b}, commit)
+
+	if err := subject.CheckLoop(mirrorcat.Mapping{Original: b, Mirror: a}, commit); err == nil {
+		t.Log("expected pushing the commit back to be refused")
+		t.Fail()
+	}
+
+	if err := subject.CheckLoop(mirrorcat.Mapping{Original: b, Mirror: c}, commit); err != nil {
+		t.Log("unexpected error: ", err)
+		t.Fail()
+	}
+
+	if err := subject.CheckLoop(mirrorcat.Mapping{Original: b, Mirror: a}, "different"); err != nil {
+		t.Log("unexpected error: ", err)
+		t.Fail()
+	}
+
+	expired := mirrorcat.NewPushHistory(-time.Second)
+	expired.Record(mirrorcat.Mapping{Original: a, Mirror: b}, commit)
+	if err := expired.CheckLoop(mirrorcat.Mapping{Original: b, Mirror: a}, commit); err != nil {
+		t.Log("unexpected error: ", err)
+		t.Fail()
+	}
+}
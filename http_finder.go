@@ -0,0 +1,175 @@
+package mirrorcat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// HTTPFinder asks an external service for the mirrors of each original.
+//
+// For each original, a GET request is sent to `Endpoint`, with the query parameters "repo" and "ref" set to the
+// Repository and Ref of the original. The service should respond with a JSON array of mirrors, in the same format
+// that RemoteRef is marshaled with:
+//
+//	[
+//	  {"repo": "https://github.com/marstr/mirrorcat.git", "ref": "master"}
+//	]
+//
+// Mirrors are published as they are read, so a large response need not be held in memory. A response of
+// 404 Not Found is treated as an empty array.
+//
+// Each attempt is abandoned after `Timeout`. Attempts which fail because of a network error, or a response of
+// 429 Too Many Requests or 5xx, are retried up to `Retries` times, waiting between them as described by `Backoff`.
+// Once any mirror has been published, a failure is never retried.
+type HTTPFinder struct {
+	Endpoint string
+
+	// Header is added to every request, for example to provide an "Authorization" header.
+	Header http.Header
+
+	// Client sends each request. If it is nil, http.DefaultClient is used.
+	Client *http.Client
+
+	Timeout time.Duration
+	Retries int
+	Backoff Backoff
+}
+
+// MaxHTTPFinderResponse is the largest response, in bytes, that an HTTPFinder will read.
+const MaxHTTPFinderResponse = 5 * 1024 * 1024
+
+// errRetryable marks an error which happened before any mirrors were published, and may succeed if retried.
+type errRetryable struct {
+	error
+}
+
+// FindMirrors asks the service for the mirrors of `original`.
+func (hf HTTPFinder) FindMirrors(ctx context.Context, original RemoteRef, results chan<- RemoteRef) (err error) {
+	defer close(results)
+
+	backoff := hf.Backoff
+	for attempt := 0; ; attempt++ {
+		err = hf.attempt(ctx, original, results)
+
+		retryable, ok := err.(errRetryable)
+		if !ok {
+			return
+		}
+		err = retryable.error
+
+		if attempt >= hf.Retries {
+			return
+		}
+		Retries.WithLabelValues("mapping-service").Inc()
+
+		select {
+		case <-time.After(backoff.Next()):
+			// Intentionally Left Blank
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// attempt sends a single request, and publishes the mirrors in its response.
+func (hf HTTPFinder) attempt(ctx context.Context, original RemoteRef, results chan<- RemoteRef) error {
+	attemptCtx := ctx
+	if hf.Timeout > 0 {
+		var cancel context.CancelFunc
+		attemptCtx, cancel = context.WithTimeout(ctx, hf.Timeout)
+		defer cancel()
+	}
+
+	endpoint, err := url.Parse(hf.Endpoint)
+	if err != nil {
+		return err
+	}
+	query := endpoint.Query()
+	query.Set("repo", original.Repository)
+	query.Set("ref", original.Ref)
+	endpoint.RawQuery = query.Encode()
+
+	req, err := http.NewRequest(http.MethodGet, endpoint.String(), &bytes.Buffer{})
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(attemptCtx)
+	req.Header.Set("Accept", "application/json")
+	for name, values := range hf.Header {
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+
+	client := hf.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return errRetryable{err}
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		io.Copy(ioutil.Discard, &io.LimitedReader{R: resp.Body, N: MaxHTTPFinderResponse})
+		return errRetryable{fmt.Errorf("%s responded with status %d", hf.Endpoint, resp.StatusCode)}
+	case resp.StatusCode != http.StatusOK:
+		return fmt.Errorf("%s responded with unexpected status %d", hf.Endpoint, resp.StatusCode)
+	}
+
+	return publishJSONRemoteRefs(ctx, &io.LimitedReader{R: resp.Body, N: MaxHTTPFinderResponse}, results)
+}
+
+// publishJSONRemoteRefs reads a JSON array of RemoteRefs, publishing them once all have been read. A RemoteRef
+// which isn't well-formed fails the lookup, and none of the others are published.
+func publishJSONRemoteRefs(ctx context.Context, body io.Reader, results chan<- RemoteRef) error {
+	decoder := json.NewDecoder(body)
+
+	if token, err := decoder.Token(); err != nil {
+		return err
+	} else if token != json.Delim('[') {
+		return fmt.Errorf("expected a JSON array of mirrors, found %v", token)
+	}
+
+	var mirrors []RemoteRef
+	for decoder.More() {
+		var mirror RemoteRef
+		if err := decoder.Decode(&mirror); err != nil {
+			return err
+		}
+
+		if err := mirror.Validate(); err != nil {
+			return fmt.Errorf("the mapping service found an invalid mirror: %v", err)
+		}
+		mirrors = append(mirrors, mirror)
+	}
+
+	if _, err := decoder.Token(); err != nil {
+		return err
+	}
+
+	for _, mirror := range mirrors {
+		select {
+		case results <- mirror:
+			// Intentionally Left Blank
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
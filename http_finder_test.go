@@ -0,0 +1,167 @@
+package mirrorcat_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Azure/mirrorcat"
+)
+
+func TestHTTPFinder_FindMirrors(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	original := mirrorcat.RemoteRef{Repository: "https://github.com/Azure/mirrorcat.git", Ref: "master"}
+	mirrors := []mirrorcat.RemoteRef{
+		{Repository: "https://github.com/marstr/mirrorcat.git", Ref: "master"},
+		{Repository: "https://github.com/haydenmc/mirrorcat.git", Ref: "dev"},
+	}
+
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		// Fail the first attempt, to ensure that it is retried.
+		if atomic.AddInt32(&requests, 1) == 1 {
+			resp.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		if got := req.Header.Get("Authorization"); got != "Bearer secret" {
+			t.Logf("got: %q want: %q", got, "Bearer secret")
+			t.Fail()
+		}
+
+		query := req.URL.Query()
+		if query.Get("repo") != original.Repository || query.Get("ref") != original.Ref {
+			resp.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		json.NewEncoder(resp).Encode(mirrors)
+	}))
+	defer server.Close()
+
+	subject := mirrorcat.HTTPFinder{
+		Endpoint: server.URL + "/mirrors",
+		Header:   http.Header{"Authorization": []string{"Bearer secret"}},
+		Timeout:  time.Second,
+		Retries:  1,
+		Backoff:  mirrorcat.Backoff{Min: time.Millisecond, Max: time.Millisecond},
+	}
+
+	got, err := collectMirrors(ctx, subject, original)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"https://github.com/haydenmc/mirrorcat.git:dev", "https://github.com/marstr/mirrorcat.git:master"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Logf("got: %v want: %v", got, want)
+		t.Fail()
+	}
+
+	if count := atomic.LoadInt32(&requests); count != 2 {
+		t.Logf("got: %d requests want: 2", count)
+		t.Fail()
+	}
+
+	got, err = collectMirrors(ctx, subject, mirrorcat.RemoteRef{Repository: "https://github.com/Azure/unknown.git", Ref: "master"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Logf("got: %v want: no mirrors", got)
+		t.Fail()
+	}
+}
+
+func TestHTTPFinder_FindMirrors_GivesUp(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		resp.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	subject := mirrorcat.HTTPFinder{
+		Endpoint: server.URL,
+		Retries:  2,
+		Backoff:  mirrorcat.Backoff{Min: time.Millisecond, Max: time.Millisecond},
+	}
+
+	if _, err := collectMirrors(ctx, subject, mirrorcat.RemoteRef{Repository: "repo", Ref: "master"}); err == nil {
+		t.Error("expected an error after every attempt failed")
+	}
+
+	if count := atomic.LoadInt32(&requests); count != 3 {
+		t.Logf("got: %d requests want: 3", count)
+		t.Fail()
+	}
+}
+
+func TestHTTPFinder_FindMirrors_InvalidMirror(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		json.NewEncoder(resp).Encode([]mirrorcat.RemoteRef{
+			{Repository: "https://github.com/marstr/mirrorcat.git", Ref: "master"},
+			{Repository: "https://github.com/marstr/mirrorcat.git", Ref: "bad..ref"},
+		})
+	}))
+	defer server.Close()
+
+	subject := mirrorcat.HTTPFinder{
+		Endpoint: server.URL,
+		Backoff:  mirrorcat.Backoff{Min: time.Millisecond, Max: time.Millisecond},
+	}
+
+	got, err := collectMirrors(ctx, subject, mirrorcat.RemoteRef{Repository: "repo", Ref: "master"})
+	if err == nil {
+		t.Error("expected an invalid mirror to fail the lookup")
+	}
+	if len(got) != 0 {
+		t.Logf("got: %v want: no mirrors", got)
+		t.Fail()
+	}
+}
+
+func TestHTTPFinder_FindMirrors_Cancellation(t *testing.T) {
+	unblock := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		select {
+		case <-unblock:
+		case <-req.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(unblock)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	subject := mirrorcat.HTTPFinder{
+		Endpoint: server.URL,
+		Retries:  5,
+		Backoff:  mirrorcat.Backoff{Min: time.Millisecond, Max: time.Millisecond},
+	}
+
+	start := time.Now()
+	_, err := collectMirrors(ctx, subject, mirrorcat.RemoteRef{Repository: "repo", Ref: "master"})
+	if err != context.DeadlineExceeded {
+		t.Logf("got: %v want: %v", err, context.DeadlineExceeded)
+		t.Fail()
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Logf("took %v to notice that the context was cancelled", elapsed)
+		t.Fail()
+	}
+}
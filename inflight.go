@@ -0,0 +1,77 @@
+package mirrorcat
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrDraining is returned by `InFlight.Start` once MirrorCat has begun to shut down, and is no longer accepting work.
+var ErrDraining = errors.New("no new work is being accepted, because MirrorCat is shutting down")
+
+// InFlight keeps track of work, like pushes to mirrors, which should be allowed to finish before MirrorCat exits.
+// Once it has begun to drain, no new work may be started.
+type InFlight struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	sync.Mutex
+	draining bool
+	work     sync.WaitGroup
+}
+
+// NewInFlight creates an InFlight that is accepting work.
+func NewInFlight() *InFlight {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &InFlight{
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Start begins a unit of work. The returned context is cancelled if the work is abandoned while draining, and `done`
+// must be called once the work has finished. Once draining has begun, ErrDraining is returned instead.
+func (inf *InFlight) Start() (ctx context.Context, done func(), err error) {
+	inf.Lock()
+	defer inf.Unlock()
+
+	if inf.draining {
+		return nil, nil, ErrDraining
+	}
+
+	inf.work.Add(1)
+	var once sync.Once
+	return inf.ctx, func() { once.Do(inf.work.Done) }, nil
+}
+
+// Draining reports whether new work is being refused.
+func (inf *InFlight) Draining() bool {
+	inf.Lock()
+	defer inf.Unlock()
+	return inf.draining
+}
+
+// Drain stops accepting new work, and waits for the work that has already started to finish. If `ctx` is cancelled
+// first, the context of each remaining unit of work is cancelled, and Drain waits for them to stop before returning
+// the error of `ctx`.
+func (inf *InFlight) Drain(ctx context.Context) error {
+	inf.Lock()
+	inf.draining = true
+	inf.Unlock()
+
+	finished := make(chan struct{})
+	go func() {
+		inf.work.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		inf.cancel()
+		return nil
+	case <-ctx.Done():
+		inf.cancel()
+		<-finished
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,68 @@
+package mirrorcat_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Azure/mirrorcat"
+)
+
+func TestInFlight_Drain(t *testing.T) {
+	subject := mirrorcat.NewInFlight()
+
+	_, done, err := subject.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		done()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	if err := subject.Drain(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := subject.Start(); err != mirrorcat.ErrDraining {
+		t.Logf("got: %v want: %v", err, mirrorcat.ErrDraining)
+		t.Fail()
+	}
+}
+
+func TestInFlight_DrainAbandons(t *testing.T) {
+	subject := mirrorcat.NewInFlight()
+
+	workCtx, done, err := subject.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The work only stops once it has been cancelled, like a git process started with its context.
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		defer done()
+		<-workCtx.Done()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if err := subject.Drain(ctx); err != context.DeadlineExceeded {
+		t.Logf("got: %v want: %v", err, context.DeadlineExceeded)
+		t.Fail()
+	}
+
+	select {
+	case <-stopped:
+		// Intentionally Left Blank
+	default:
+		t.Log("Drain returned before the abandoned work stopped")
+		t.Fail()
+	}
+}
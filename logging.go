@@ -0,0 +1,57 @@
+package mirrorcat
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// These are the names of the fields that are attached to MirrorCat's log entries, so that each of the entries about a
+// single webhook delivery, or push job, can be found together.
+const (
+	LogFieldDelivery = "delivery"
+	LogFieldJob      = "job"
+	LogFieldOriginal = "original"
+	LogFieldMirror   = "mirror"
+	LogFieldFinder   = "finder"
+	LogFieldStage    = "stage"
+)
+
+// Logger is the log entry that every other entry written by MirrorCat is derived from, when one hasn't been attached
+// to a context with `WithLogger`. Replace it to change where MirrorCat logs to, or to add fields to every entry.
+var Logger = logrus.NewEntry(logrus.StandardLogger())
+
+type loggerKey struct{}
+
+// WithLogger attaches a log entry to a context, so that everything done on behalf of that context is logged with the
+// entry's fields.
+func WithLogger(ctx context.Context, entry *logrus.Entry) context.Context {
+	return context.WithValue(ctx, loggerKey{}, entry)
+}
+
+// LoggerFrom finds the log entry attached to a context by `WithLogger`, falling back to `Logger`.
+func LoggerFrom(ctx context.Context) *logrus.Entry {
+	if entry, ok := ctx.Value(loggerKey{}).(*logrus.Entry); ok {
+		return entry
+	}
+	return Logger
+}
+
+// LogRef describes a RemoteRef for use as the value of a log field, like "https://github.com/Azure/mirrorcat.git#master".
+// Credentials are removed from its repository.
+func LogRef(ref RemoteRef) string {
+	return fmt.Sprintf("%s#%s", RedactCredentials(ref.Repository), ref.Ref)
+}
+
+// ParseLogFormat finds the formatter for a log format, either "logfmt" or "json".
+func ParseLogFormat(name string) (logrus.Formatter, error) {
+	switch name {
+	case "logfmt":
+		return &logrus.TextFormatter{DisableColors: true, FullTimestamp: true}, nil
+	case "json":
+		return &logrus.JSONFormatter{}, nil
+	default:
+		return nil, fmt.Errorf("%q is not a recognized log format, use \"logfmt\" or \"json\"", name)
+	}
+}
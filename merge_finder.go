@@ -5,33 +5,104 @@ import "context"
 // MergeFinder allows a mechanism to find mirror mappings from multiple underlying MirrorFinders.
 type MergeFinder []MirrorFinder
 
-// FindMirrors enumerates each MirrorFinder, and
+// FindMirrors enumerates each MirrorFinder, and publishes each of the mirrors that they find.
+//
+// Children are consulted from highest to lowest priority, see `Prioritized`. Before any child in a tier of
+// equal priority is asked for mirrors, each child in that tier which is an `ExclusionFinder` is asked which
+// mirrors should be ignored. Those exclusions apply to the remainder of that tier, and to every lower tier.
+//
+// Each child is asked for mirrors within a span of its own, see `StartSpan`.
 func (haystack MergeFinder) FindMirrors(ctx context.Context, needle RemoteRef, results chan<- RemoteRef) (err error) {
 	defer close(results)
 
+	excluded := make(map[RemoteRef]struct{})
+
+	for _, tier := range tiersOf(haystack) {
+		err = findExclusions(ctx, tier, needle, excluded)
+		if err != nil {
+			return
+		}
+
+		overridden := false
+		for _, finder := range tier {
+			// Due to the fact that all FindMirrors implementations must close the results channel to communicate
+			// that no more matches have been found, we must create a layer of separation between the merged results
+			// and the results from each child MirrorFinder.
+			intermediate := make(chan RemoteRef)
+
+			errs := make(chan error, 1)
+
+			// Kick-off a goroutine to fetch the child's matching mirrors.
+			go func(finder MirrorFinder) {
+				select {
+				case errs <- findMirrorsTraced(ctx, finder, needle, intermediate):
+					// Intentionally Left Blank
+				case <-ctx.Done():
+					// This case prevents leaking this goroutine in the case that the underlying type
+					// of `finder` does not respect cancellation tokens appropriately.
+					errs <- ctx.Err()
+				}
+			}(finder)
+
+			found := false
+			for mirror := range intermediate {
+				found = true
+				if _, ok := excluded[mirror.Canonical()]; ok {
+					continue
+				}
+
+				select {
+				case results <- mirror:
+					// Intentionally Left Blank
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+
+			err = <-errs
+			if err != nil {
+				return
+			}
+
+			if found && overrides(finder) {
+				overridden = true
+			}
+		}
+
+		if overridden {
+			return
+		}
+	}
+	return
+}
+
+// ListMirrors enumerates each child which is also a MirrorLister, publishing all of their mappings. Children
+// which are not able to list their mappings are skipped.
+func (haystack MergeFinder) ListMirrors(ctx context.Context, results chan<- Mapping) (err error) {
+	defer close(results)
+
 	for _, finder := range haystack {
-		// Due to the fact that all FindMirrors implementations must close the results channel to communicate
-		// that no more matches have been found, we must create a layer of separation between the merged results
-		// and the results from each child MirrorFinder.
-		intermediate := make(chan RemoteRef)
+		lister, ok := finder.(MirrorLister)
+		if !ok {
+			continue
+		}
+
+		intermediate := make(chan Mapping)
 
 		errs := make(chan error, 1)
 
-		// Kick-off a goroutine to fetch the child's matching mirrors.
 		go func() {
 			select {
-			case errs <- finder.FindMirrors(ctx, needle, intermediate):
+			case errs <- lister.ListMirrors(ctx, intermediate):
 				// Intentionally Left Blank
 			case <-ctx.Done():
-				// This case prevents leaking this goroutine in the case that the underlying type
-				// of `finder` does not respect cancellation tokens appropriately.
 				errs <- ctx.Err()
 			}
 		}()
 
-		for mirror := range intermediate {
+		for mapping := range intermediate {
 			select {
-			case results <- mirror:
+			case results <- mapping:
 				// Intentionally Left Blank
 			case <-ctx.Done():
 				return ctx.Err()
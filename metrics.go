@@ -0,0 +1,176 @@
+package mirrorcat
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+// These metrics describe the work that MirrorCat has done since it started. They are not exported to Prometheus until
+// they are registered with `RegisterMetrics`.
+//
+// No label ever holds a URL, only the name of a host, so that credentials are never exposed by the metrics endpoint.
+var (
+	// WebhooksReceived counts the webhooks that MirrorCat has been sent, by the service that sent them and the kind of
+	// event that they describe.
+	WebhooksReceived = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "mirrorcat",
+		Name:      "webhooks_received_total",
+		Help:      "The number of webhooks received, by provider and event type.",
+	}, []string{"provider", "event"})
+
+	// FinderLookups counts the times that each kind of MirrorFinder has been asked for mirrors, and whether it
+	// was able to answer.
+	FinderLookups = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "mirrorcat",
+		Name:      "finder_lookups_total",
+		Help:      "The number of times each source of mappings was asked for mirrors, by outcome.",
+	}, []string{"finder", "outcome"})
+
+	// FinderLookupDuration measures how long each kind of MirrorFinder takes to find the mirrors of an original.
+	FinderLookupDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "mirrorcat",
+		Name:      "finder_lookup_duration_seconds",
+		Help:      "How long each source of mappings took to find mirrors.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"finder"})
+
+	// GitDuration measures how long each git command run while pushing takes, by the stage of the push that it
+	// belongs to: "clone", "remote-add", "ls-remote", or "push".
+	GitDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "mirrorcat",
+		Name:      "git_duration_seconds",
+		Help:      "How long each stage of a push took, by stage and outcome.",
+		Buckets:   prometheus.ExponentialBuckets(0.1, 2, 12),
+	}, []string{"stage", "outcome"})
+
+	// Pushes counts the mirrors that have been pushed to, by the host that they are on and whether the push
+	// succeeded.
+	Pushes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "mirrorcat",
+		Name:      "pushes_total",
+		Help:      "The number of pushes to mirrors, by destination host and outcome.",
+	}, []string{"host", "outcome"})
+
+	// PushQueueDepth is the number of mirrors which are waiting to be, or are being, pushed to.
+	PushQueueDepth = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "mirrorcat",
+		Name:      "push_queue_depth",
+		Help:      "The number of mirrors waiting to be, or being, pushed to.",
+	})
+
+	// Retries counts the operations which MirrorCat has attempted again after they failed.
+	Retries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "mirrorcat",
+		Name:      "retries_total",
+		Help:      "The number of times an operation was retried, by operation.",
+	}, []string{"operation"})
+)
+
+// RegisterMetrics allows each of MirrorCat's metrics to be collected by `registerer`.
+func RegisterMetrics(registerer prometheus.Registerer) error {
+	collectors := []prometheus.Collector{
+		WebhooksReceived,
+		FinderLookups,
+		FinderLookupDuration,
+		GitDuration,
+		Pushes,
+		PushQueueDepth,
+		Retries,
+	}
+
+	for _, collector := range collectors {
+		if err := registerer.Register(collector); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// outcomeOf names the outcome of an operation for use as the "outcome" label of a metric.
+func outcomeOf(err error) string {
+	if err == nil {
+		return "success"
+	}
+	return "failure"
+}
+
+// HostOf finds the name of the host that a repository is on, without any credentials that its URL holds. Repositories
+// written in the SCP-like syntax that git accepts, like "git@github.com:Azure/mirrorcat.git", are understood. The
+// host of a repository on the local file system is "local".
+func HostOf(repository string) string {
+	if parsed, err := url.Parse(repository); err == nil && parsed.Host != "" {
+		return strings.ToLower(parsed.Hostname())
+	}
+
+	if colon := strings.IndexRune(repository, ':'); colon > 0 && !strings.ContainsRune(repository[:colon], '/') {
+		host := repository[:colon]
+		if at := strings.LastIndex(host, "@"); at >= 0 {
+			host = host[at+1:]
+		}
+		return strings.ToLower(host)
+	}
+	return "local"
+}
+
+// InstrumentedFinder decorates a MirrorFinder, recording how often it is asked for mirrors and how long it takes
+// to answer in the `FinderLookups` and `FinderLookupDuration` metrics. Each lookup is also logged.
+type InstrumentedFinder struct {
+	MirrorFinder
+
+	// Name identifies the kind of MirrorFinder being decorated, like "redis" or "sql", in each metric.
+	Name string
+}
+
+// FindMirrors passes along the mirrors found by the decorated MirrorFinder.
+func (inf InstrumentedFinder) FindMirrors(ctx context.Context, original RemoteRef, results chan<- RemoteRef) error {
+	started := time.Now()
+	err := inf.MirrorFinder.FindMirrors(ctx, original, results)
+	elapsed := time.Since(started)
+
+	FinderLookupDuration.WithLabelValues(inf.Name).Observe(elapsed.Seconds())
+	FinderLookups.WithLabelValues(inf.Name, outcomeOf(err)).Inc()
+
+	logger := LoggerFrom(ctx).WithFields(logrus.Fields{
+		LogFieldFinder:   inf.Name,
+		LogFieldOriginal: LogRef(original),
+		"duration":       elapsed.String(),
+	})
+	if err != nil {
+		logger.WithError(err).Warn("Unable to find mirrors")
+	} else {
+		logger.Debug("Found mirrors")
+	}
+	return err
+}
+
+// FindExclusions passes along the exclusions of the decorated MirrorFinder, if it is an ExclusionFinder.
+func (inf InstrumentedFinder) FindExclusions(ctx context.Context, original RemoteRef, results chan<- RemoteRef) error {
+	if excluder, ok := inf.MirrorFinder.(ExclusionFinder); ok {
+		return excluder.FindExclusions(ctx, original, results)
+	}
+	close(results)
+	return nil
+}
+
+// ListMirrors passes along the mappings of the decorated MirrorFinder, if it is a MirrorLister.
+func (inf InstrumentedFinder) ListMirrors(ctx context.Context, results chan<- Mapping) error {
+	if lister, ok := inf.MirrorFinder.(MirrorLister); ok {
+		return lister.ListMirrors(ctx, results)
+	}
+	close(results)
+	return nil
+}
+
+// FindOptions passes along the options found by the decorated MirrorFinder, if it is an OptionsFinder. Otherwise,
+// the zero value of MirrorOptions is returned.
+func (inf InstrumentedFinder) FindOptions(m Mapping) (MirrorOptions, error) {
+	if optioner, ok := inf.MirrorFinder.(OptionsFinder); ok {
+		return optioner.FindOptions(m)
+	}
+	return MirrorOptions{}, nil
+}
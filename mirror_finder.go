@@ -17,6 +17,12 @@ type MirrorFinder interface {
 	FindMirrors(context.Context, RemoteRef, chan<- RemoteRef) error
 }
 
+// MirrorLister is implemented by MirrorFinders that are able to enumerate every mapping they know about.
+// Just like `FindMirrors`, implementations must close the results channel once all mappings have been published.
+type MirrorLister interface {
+	ListMirrors(context.Context, chan<- Mapping) error
+}
+
 // DefaultMirrorFinder provides an in-memory location for storing information about
 // which branches should mirror which others.
 //
@@ -24,13 +30,15 @@ type MirrorFinder interface {
 // 	functionality
 type DefaultMirrorFinder struct {
 	sync.RWMutex
-	underlyer map[RemoteRef][]RemoteRef
+	underlyer  map[RemoteRef][]RemoteRef
+	exclusions map[RemoteRef][]RemoteRef
 }
 
 // NewDefaultMirrorFinder creates an empty instance of a MirrorFinder
 func NewDefaultMirrorFinder() *DefaultMirrorFinder {
 	return &DefaultMirrorFinder{
-		underlyer: make(map[RemoteRef][]RemoteRef),
+		underlyer:  make(map[RemoteRef][]RemoteRef),
+		exclusions: make(map[RemoteRef][]RemoteRef),
 	}
 }
 
@@ -50,9 +58,36 @@ func (dmf *DefaultMirrorFinder) ClearMirrors(original RemoteRef) {
 	delete(dmf.underlyer, original)
 }
 
+// RemoveMirrors removes the association between a particular `RemoteRef` and each of `branches`, leaving any other
+// mirrored copies in place.
+func (dmf *DefaultMirrorFinder) RemoveMirrors(original RemoteRef, branches ...RemoteRef) {
+	dmf.Lock()
+	defer dmf.Unlock()
+
+	remaining := differenceOf(dmf.underlyer[original], branches)
+	if len(remaining) == 0 {
+		delete(dmf.underlyer, original)
+		return
+	}
+	dmf.underlyer[original] = remaining
+}
+
+// AddExclusions registers branches that should not be pushed to when `original` is updated, even if
+// another MirrorFinder of equal or lower priority believes they should be.
+func (dmf *DefaultMirrorFinder) AddExclusions(original RemoteRef, branches ...RemoteRef) {
+	dmf.Lock()
+	defer dmf.Unlock()
+
+	dmf.exclusions[original] = append(dmf.exclusions[original], branches...)
+}
+
 // ClearAll removes all associations between References
 func (dmf *DefaultMirrorFinder) ClearAll() {
+	dmf.Lock()
+	defer dmf.Unlock()
+
 	dmf.underlyer = make(map[RemoteRef][]RemoteRef)
+	dmf.exclusions = make(map[RemoteRef][]RemoteRef)
 }
 
 // FindMirrors iterates through the entries that had been added and publishes them all to `results`
@@ -72,3 +107,59 @@ func (dmf *DefaultMirrorFinder) FindMirrors(ctx context.Context, original Remote
 	}
 	return nil
 }
+
+// FindExclusions publishes each branch that was registered with `AddExclusions` for `original` to `results`.
+func (dmf *DefaultMirrorFinder) FindExclusions(ctx context.Context, original RemoteRef, results chan<- RemoteRef) error {
+	dmf.RLock()
+	defer dmf.RUnlock()
+	defer close(results)
+
+	for _, m := range dmf.exclusions[original] {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case results <- m:
+			// Intentionally Left Blank
+		}
+	}
+	return nil
+}
+
+// ListMirrors publishes every mapping that has been added to `results`, ordered by original.
+func (dmf *DefaultMirrorFinder) ListMirrors(ctx context.Context, results chan<- Mapping) error {
+	return dmf.list(ctx, false, results)
+}
+
+// ListExclusions publishes every exclusion that has been added to `results`, ordered by original.
+func (dmf *DefaultMirrorFinder) ListExclusions(ctx context.Context, results chan<- Mapping) error {
+	return dmf.list(ctx, true, results)
+}
+
+func (dmf *DefaultMirrorFinder) list(ctx context.Context, exclusions bool, results chan<- Mapping) error {
+	dmf.RLock()
+	defer dmf.RUnlock()
+	defer close(results)
+
+	tree := dmf.underlyer
+	if exclusions {
+		tree = dmf.exclusions
+	}
+
+	originals := make([]RemoteRef, 0, len(tree))
+	for original := range tree {
+		originals = append(originals, original)
+	}
+	sortRemoteRefs(originals)
+
+	for _, original := range originals {
+		for _, m := range tree[original] {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case results <- Mapping{Original: original, Mirror: m}:
+				// Intentionally Left Blank
+			}
+		}
+	}
+	return nil
+}
@@ -100,3 +100,49 @@ func TestDefaultMirrorFinder_FindMirrors_RespectsCancellation(t *testing.T) {
 		t.Error("timed out")
 	}
 }
+
+func ExampleDefaultMirrorFinder_ListMirrors() {
+	subject := mirrorcat.NewDefaultMirrorFinder()
+	subject.AddMirrors(
+		mirrorcat.RemoteRef{Repository: "https://github.com/Azure/mirrorcat", Ref: "master"},
+		mirrorcat.RemoteRef{Repository: "https://github.com/marstr/mirrorcat", Ref: "master"})
+	subject.AddMirrors(
+		mirrorcat.RemoteRef{Repository: "https://github.com/Azure/azure-sdk-for-go", Ref: "master"},
+		mirrorcat.RemoteRef{Repository: "https://github.com/Azure/azure-sdk-for-go", Ref: "dev"})
+
+	mappings, err := mirrorcat.CollectMappings(context.Background(), subject)
+	if err != nil {
+		return
+	}
+
+	for _, m := range mappings {
+		fmt.Println(m.Original.Repository, m.Original.Ref, "->", m.Mirror.Repository, m.Mirror.Ref)
+	}
+
+	// Output:
+	// https://github.com/Azure/azure-sdk-for-go master -> https://github.com/Azure/azure-sdk-for-go dev
+	// https://github.com/Azure/mirrorcat master -> https://github.com/marstr/mirrorcat master
+}
+
+func ExampleDefaultMirrorFinder_RemoveMirrors() {
+	original := mirrorcat.RemoteRef{Repository: "https://github.com/Azure/mirrorcat", Ref: "master"}
+
+	subject := mirrorcat.NewDefaultMirrorFinder()
+	subject.AddMirrors(
+		original,
+		mirrorcat.RemoteRef{Repository: "https://github.com/marstr/mirrorcat", Ref: "master"},
+		mirrorcat.RemoteRef{Repository: "https://github.com/haydenmc/mirrorcat", Ref: "master"})
+	subject.RemoveMirrors(original, mirrorcat.RemoteRef{Repository: "https://github.com/marstr/mirrorcat", Ref: "master"})
+
+	mappings, err := mirrorcat.CollectMappings(context.Background(), subject)
+	if err != nil {
+		return
+	}
+
+	for _, m := range mappings {
+		fmt.Println(m.Original.Repository, m.Original.Ref, "->", m.Mirror.Repository, m.Mirror.Ref)
+	}
+
+	// Output:
+	// https://github.com/Azure/mirrorcat master -> https://github.com/haydenmc/mirrorcat master
+}
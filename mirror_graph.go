@@ -0,0 +1,182 @@
+package mirrorcat
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// Mapping pairs an original `RemoteRef` with one of the `RemoteRef`s that should mirror it.
+type Mapping struct {
+	Original RemoteRef `json:"original"`
+	Mirror   RemoteRef `json:"mirror"`
+}
+
+// IsSelfMirror determines whether or not a Mapping would push a ref on top of itself.
+func (m Mapping) IsSelfMirror() bool {
+	return m.Original.Canonical() == m.Mirror.Canonical()
+}
+
+// Canonical strips away differences in spelling that do not change which branch is being
+// identified, so that two RemoteRefs naming the same branch compare as equal.
+func (rr RemoteRef) Canonical() RemoteRef {
+	repo := strings.TrimSuffix(rr.Repository, "/")
+	repo = strings.TrimSuffix(repo, ".git")
+	return RemoteRef{
+		Repository: repo,
+		Ref:        NormalizeRef(rr.Ref),
+	}
+}
+
+// FindCycles inspects a set of mappings for groups of RemoteRefs which, if a push were to
+// arrive at any one of them, would cause mirrored pushes to bounce between each other forever.
+//
+// Each cycle is reported as the set of RemoteRefs participating in it, in a stable order.
+// Mappings which target themselves are not reported here, see `Mapping.IsSelfMirror`.
+func FindCycles(mappings []Mapping) (cycles [][]RemoteRef) {
+	components, spelling := findComponents(mappings)
+	for _, component := range components {
+		cycle := make([]RemoteRef, 0, len(component))
+		for _, member := range component {
+			cycle = append(cycle, spelling[member])
+		}
+		sortRemoteRefs(cycle)
+		cycles = append(cycles, cycle)
+	}
+
+	sort.Slice(cycles, func(i, j int) bool {
+		return lessRemoteRef(cycles[i][0], cycles[j][0])
+	})
+	return
+}
+
+// RejectMappings separates mappings that are safe to act upon from those that would either mirror a ref
+// onto itself, or that participate in a cycle as described by `FindCycles`.
+func RejectMappings(mappings []Mapping) (accepted []Mapping, rejected []Rejection) {
+	components, _ := findComponents(mappings)
+	membership := make(map[RemoteRef]int)
+	for i, component := range components {
+		for _, member := range component {
+			membership[member] = i
+		}
+	}
+
+	now := time.Now()
+	for _, m := range mappings {
+		if m.IsSelfMirror() {
+			rejected = append(rejected, Rejection{Mapping: m, Reason: RejectedSelfMirror, Time: now})
+			continue
+		}
+
+		origComponent, origInCycle := membership[m.Original.Canonical()]
+		mirrorComponent, mirrorInCycle := membership[m.Mirror.Canonical()]
+		if origInCycle && mirrorInCycle && origComponent == mirrorComponent {
+			rejected = append(rejected, Rejection{Mapping: m, Reason: RejectedCycle, Time: now})
+			continue
+		}
+
+		accepted = append(accepted, m)
+	}
+	return
+}
+
+// findComponents identifies each strongly connected component in the graph described by `mappings` that has more
+// than one member. Members are identified by their canonical form, `spelling` maps them back to how they were
+// first written in `mappings`.
+func findComponents(mappings []Mapping) (components [][]RemoteRef, spelling map[RemoteRef]RemoteRef) {
+	graph := make(map[RemoteRef][]RemoteRef)
+	spelling = make(map[RemoteRef]RemoteRef)
+	for _, m := range mappings {
+		if m.IsSelfMirror() {
+			continue
+		}
+		orig, mirror := m.Original.Canonical(), m.Mirror.Canonical()
+		graph[orig] = append(graph[orig], mirror)
+		if _, ok := spelling[orig]; !ok {
+			spelling[orig] = m.Original
+		}
+		if _, ok := spelling[mirror]; !ok {
+			spelling[mirror] = m.Mirror
+		}
+	}
+
+	nodes := make([]RemoteRef, 0, len(spelling))
+	for node := range spelling {
+		nodes = append(nodes, node)
+	}
+	sortRemoteRefs(nodes)
+
+	// What follows is Tarjan's algorithm for finding strongly connected components. Any component
+	// with more than one member describes a cycle.
+	index := make(map[RemoteRef]int, len(nodes))
+	lowLink := make(map[RemoteRef]int, len(nodes))
+	onStack := make(map[RemoteRef]bool, len(nodes))
+	var stack []RemoteRef
+
+	var connect func(RemoteRef)
+	connect = func(node RemoteRef) {
+		index[node] = len(index)
+		lowLink[node] = index[node]
+		stack = append(stack, node)
+		onStack[node] = true
+
+		for _, next := range graph[node] {
+			if _, visited := index[next]; !visited {
+				connect(next)
+				if lowLink[next] < lowLink[node] {
+					lowLink[node] = lowLink[next]
+				}
+			} else if onStack[next] && index[next] < lowLink[node] {
+				lowLink[node] = index[next]
+			}
+		}
+
+		if lowLink[node] != index[node] {
+			return
+		}
+
+		var component []RemoteRef
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			component = append(component, top)
+			if top == node {
+				break
+			}
+		}
+
+		if len(component) > 1 {
+			components = append(components, component)
+		}
+	}
+
+	for _, node := range nodes {
+		if _, visited := index[node]; !visited {
+			connect(node)
+		}
+	}
+	return
+}
+
+func sortRemoteRefs(refs []RemoteRef) {
+	sort.Slice(refs, func(i, j int) bool {
+		return lessRemoteRef(refs[i], refs[j])
+	})
+}
+
+func lessRemoteRef(a, b RemoteRef) bool {
+	if a.Repository != b.Repository {
+		return a.Repository < b.Repository
+	}
+	return a.Ref < b.Ref
+}
+
+func sortMappings(mappings []Mapping) {
+	sort.Slice(mappings, func(i, j int) bool {
+		if mappings[i].Original != mappings[j].Original {
+			return lessRemoteRef(mappings[i].Original, mappings[j].Original)
+		}
+		return lessRemoteRef(mappings[i].Mirror, mappings[j].Mirror)
+	})
+}
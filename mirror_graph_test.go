@@ -0,0 +1,92 @@
+package mirrorcat_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/Azure/mirrorcat"
+)
+
+func ExampleFindCycles() {
+	a := mirrorcat.RemoteRef{Repository: "https://github.com/Azure/mirrorcat", Ref: "master"}
+	b := mirrorcat.RemoteRef{Repository: "https://github.com/marstr/mirrorcat", Ref: "master"}
+	c := mirrorcat.RemoteRef{Repository: "https://github.com/marstr/mirrorcat", Ref: "dev"}
+
+	cycles := mirrorcat.FindCycles([]mirrorcat.Mapping{
+		{Original: a, Mirror: b},
+		{Original: b, Mirror: a},
+		{Original: b, Mirror: c},
+	})
+
+	for _, cycle := range cycles {
+		for _, member := range cycle {
+			fmt.Println(member.Repository, member.Ref)
+		}
+	}
+
+	// Output:
+	// https://github.com/Azure/mirrorcat master
+	// https://github.com/marstr/mirrorcat master
+}
+
+func TestFindCycles(t *testing.T) {
+	a := mirrorcat.RemoteRef{Repository: "https://github.com/Azure/mirrorcat", Ref: "master"}
+	b := mirrorcat.RemoteRef{Repository: "https://github.com/marstr/mirrorcat", Ref: "master"}
+	c := mirrorcat.RemoteRef{Repository: "https://github.com/haydenmc/mirrorcat", Ref: "master"}
+	d := mirrorcat.RemoteRef{Repository: "https://github.com/haydenmc/mirrorcat", Ref: "dev"}
+
+	testCases := []struct {
+		name     string
+		mappings []mirrorcat.Mapping
+		want     int
+	}{
+		{"empty", nil, 0},
+		{"chain", []mirrorcat.Mapping{{Original: a, Mirror: b}, {Original: b, Mirror: c}}, 0},
+		{"self", []mirrorcat.Mapping{{Original: a, Mirror: a}}, 0},
+		{"pair", []mirrorcat.Mapping{{Original: a, Mirror: b}, {Original: b, Mirror: a}}, 1},
+		{"triangle", []mirrorcat.Mapping{{Original: a, Mirror: b}, {Original: b, Mirror: c}, {Original: c, Mirror: a}}, 1},
+		{"two", []mirrorcat.Mapping{{Original: a, Mirror: b}, {Original: b, Mirror: a}, {Original: c, Mirror: d}, {Original: d, Mirror: c}}, 2},
+		{"spelling", []mirrorcat.Mapping{
+			{Original: a, Mirror: b},
+			{Original: mirrorcat.RemoteRef{Repository: b.Repository + ".git", Ref: "refs/heads/master"}, Mirror: a},
+		}, 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := mirrorcat.FindCycles(tc.mappings); len(got) != tc.want {
+				t.Logf("got: %d cycles (%v) want: %d", len(got), got, tc.want)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestMapping_IsSelfMirror(t *testing.T) {
+	testCases := []struct {
+		mirrorcat.Mapping
+		want bool
+	}{
+		{mirrorcat.Mapping{
+			Original: mirrorcat.RemoteRef{Repository: "https://github.com/Azure/mirrorcat", Ref: "master"},
+			Mirror:   mirrorcat.RemoteRef{Repository: "https://github.com/Azure/mirrorcat", Ref: "master"},
+		}, true},
+		{mirrorcat.Mapping{
+			Original: mirrorcat.RemoteRef{Repository: "https://github.com/Azure/mirrorcat.git", Ref: "refs/heads/master"},
+			Mirror:   mirrorcat.RemoteRef{Repository: "https://github.com/Azure/mirrorcat", Ref: "master"},
+		}, true},
+		{mirrorcat.Mapping{
+			Original: mirrorcat.RemoteRef{Repository: "https://github.com/Azure/mirrorcat", Ref: "master"},
+			Mirror:   mirrorcat.RemoteRef{Repository: "https://github.com/Azure/mirrorcat", Ref: "dev"},
+		}, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run("", func(t *testing.T) {
+			if got := tc.IsSelfMirror(); got != tc.want {
+				t.Logf("got: %v want: %v for %v", got, tc.want, tc.Mapping)
+				t.Fail()
+			}
+		})
+	}
+}
@@ -0,0 +1,246 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Azure/mirrorcat"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// DefaultAuditMaxMegabytes is the size at which the audit log in --audit-dir is rotated, if not specified by the invoker
+// of MirrorCat. DefaultAuditMaxFiles keeps every rotated file, so that history is only ever removed when asked to.
+const (
+	DefaultAuditMaxMegabytes = 100
+	DefaultAuditMaxFiles     = 0
+)
+
+// auditLog holds every sink that "mirrorcat start" records pushes in. It is empty unless --audit-dir or
+// --audit-redis-stream is set.
+var auditLog mirrorcat.AuditSinks
+
+// recordAudit stores a record in every audit sink. Failing to do so shouldn't prevent further pushes, or changes to
+// mappings, so errors are only logged.
+func recordAudit(record mirrorcat.AuditRecord) {
+	if len(auditLog) == 0 {
+		return
+	}
+
+	// The push has already happened, so it should be recorded even if the request which caused it has timed out.
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := auditLog.Audit(ctx, record); err != nil {
+		mirrorcat.Logger.WithError(err).WithFields(logrus.Fields{
+			mirrorcat.LogFieldDelivery: record.DeliveryID,
+			mirrorcat.LogFieldJob:      record.JobID,
+			mirrorcat.LogFieldOriginal: mirrorcat.LogRef(record.Original),
+			mirrorcat.LogFieldMirror:   mirrorcat.LogRef(record.Mirror),
+		}).Error("Unable to write to audit log")
+	}
+}
+
+// auditRecordOf describes the outcome of pushing to a single mirror.
+func auditRecordOf(jobID string, original, mirror mirrorcat.RemoteRef, result mirrorcat.PushResult, deliveryID, pusher string) mirrorcat.AuditRecord {
+	record := mirrorcat.AuditRecord{
+		Time:       time.Now(),
+		DeliveryID: deliveryID,
+		Pusher:     pusher,
+		JobID:      jobID,
+		Original:   original,
+		Mirror:     withoutCredentials(mirror),
+		OldSHA:     result.Before,
+		NewSHA:     result.After,
+		Outcome:    mirrorcat.JobSucceeded,
+	}
+
+	if source, ok := sourceOf(mirrorcat.Mapping{Original: original, Mirror: mirror}); ok {
+		record.Source = source
+	}
+
+	if result.Err != nil {
+		record.Outcome = mirrorcat.JobFailed
+		if job, ok := jobHistory.Get(jobID); ok {
+			record.Error = job.Error
+		} else {
+			record.Error = mirrorcat.RedactCredentials(result.Err.Error())
+		}
+	}
+	return record
+}
+
+// newFileAuditSink creates the sink for --audit-dir, using the configured limits.
+func newFileAuditSink(dir string) (*mirrorcat.FileAuditSink, error) {
+	return mirrorcat.NewFileAuditSink(dir, int64(viper.GetInt("audit-max-megabytes"))*1024*1024, viper.GetInt("audit-max-files"))
+}
+
+// auditCmd represents the audit command
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Prints the record of each push that MirrorCat has made, and each mapping change it saw.",
+	Long: `Prints the audit log written by "mirrorcat start", most recent first. By default, it is
+read from the same --audit-dir that "mirrorcat start" writes to, or from the Redis stream
+named by --audit-redis-stream if there is no directory.
+
+Records may be filtered by the repository or ref of either their original or mirror, the
+webhook delivery which caused them, who pushed to the original, their outcome, and when
+they were made.
+
+Mirrors that "mirrorcat start" saw being added to, removed from, or expiring in, Redis
+are also recorded, with an OUTCOME like "mapping added".`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		format, _ := cmd.Flags().GetString("output")
+		if format != "table" && format != "json" {
+			fmt.Fprintf(os.Stderr, "unrecognized output format %q\n", format)
+			os.Exit(1)
+		}
+
+		query, err := auditQueryOf(cmd)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+
+		reader, closeReader, err := openAuditReader(cmd)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+		defer closeReader()
+
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		records, err := reader.ReadAudit(ctx, query)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+
+		if format == "json" {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			encoder.Encode(records)
+			return
+		}
+
+		short := func(sha string) string {
+			if len(sha) > 7 {
+				return sha[:7]
+			}
+			return sha
+		}
+
+		// Records of a mapping changing name the change, where those of a push name their outcome.
+		table := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(table, "TIME\tDELIVERY\tPUSHER\tORIGINAL\tORIGINAL REF\tMIRROR\tMIRROR REF\tOLD\tNEW\tOUTCOME")
+		for _, r := range records {
+			outcome := r.Outcome.String()
+			if r.Change != "" {
+				outcome = "mapping " + string(r.Change)
+			}
+			fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", r.Time.Format(time.RFC3339), r.DeliveryID, r.Pusher, r.Original.Repository, r.Original.Ref, r.Mirror.Repository, r.Mirror.Ref, short(r.OldSHA), short(r.NewSHA), outcome)
+		}
+		table.Flush()
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(auditCmd)
+
+	auditCmd.Flags().String("audit-dir", "", "A directory of audit log files to read. Defaults to the same directory \"mirrorcat start\" would write to.")
+	auditCmd.Flags().StringP("redis-connection", "r", "", "The URL of a single Redis server to read the audit stream from. Defaults to the same Redis deployment \"mirrorcat start\" would use.")
+	auditCmd.Flags().String("audit-redis-stream", "", "The Redis stream to read. Defaults to the same stream \"mirrorcat start\" would write to.")
+
+	auditCmd.Flags().String("repo", "", "Only print pushes whose original or mirror is this repository.")
+	auditCmd.Flags().String("ref", "", "Only print pushes whose original or mirror is this ref.")
+	auditCmd.Flags().String("delivery", "", "Only print pushes caused by this webhook delivery.")
+	auditCmd.Flags().String("pusher", "", "Only print pushes caused by this person pushing to the original.")
+	auditCmd.Flags().String("outcome", "", "Only print pushes which \"succeeded\" or \"failed\".")
+	auditCmd.Flags().String("since", "", "Only print pushes made at or after this RFC 3339 time.")
+	auditCmd.Flags().String("until", "", "Only print pushes made at or before this RFC 3339 time.")
+	auditCmd.Flags().Int("limit", 100, "The largest number of pushes to print. Zero prints every push.")
+
+	auditCmd.Flags().Duration("timeout", time.Minute, "The longest amount of time to spend reading the audit log.")
+	auditCmd.Flags().StringP("output", "o", "table", "The format of the records that are written. Either \"table\" or \"json\".")
+}
+
+// auditQueryOf reads the flags of "mirrorcat audit" which select records.
+func auditQueryOf(cmd *cobra.Command) (query mirrorcat.AuditQuery, err error) {
+	query.Repository, _ = cmd.Flags().GetString("repo")
+	query.Ref, _ = cmd.Flags().GetString("ref")
+	query.DeliveryID, _ = cmd.Flags().GetString("delivery")
+	query.Pusher, _ = cmd.Flags().GetString("pusher")
+	query.Limit, _ = cmd.Flags().GetInt("limit")
+
+	if raw, _ := cmd.Flags().GetString("outcome"); raw != "" {
+		var outcome mirrorcat.JobStatus
+		if outcome, err = mirrorcat.ParseJobStatus(raw); err != nil {
+			return
+		}
+		query.Outcome = &outcome
+	}
+
+	if raw, _ := cmd.Flags().GetString("since"); raw != "" {
+		if query.Since, err = time.Parse(time.RFC3339, raw); err != nil {
+			err = fmt.Errorf("--since must be an RFC 3339 time: %v", err)
+			return
+		}
+	}
+
+	if raw, _ := cmd.Flags().GetString("until"); raw != "" {
+		if query.Until, err = time.Parse(time.RFC3339, raw); err != nil {
+			err = fmt.Errorf("--until must be an RFC 3339 time: %v", err)
+			return
+		}
+	}
+	return
+}
+
+// openAuditReader chooses the audit log that "mirrorcat audit" reads. Naming a Redis server or stream on the command
+// line reads from Redis, otherwise the directory that "mirrorcat start" would write to is preferred. The returned
+// function releases any connection that was opened.
+func openAuditReader(cmd *cobra.Command) (reader mirrorcat.AuditReader, closeReader func(), err error) {
+	closeReader = func() {}
+
+	dir, _ := cmd.Flags().GetString("audit-dir")
+	connection, _ := cmd.Flags().GetString("redis-connection")
+	stream, _ := cmd.Flags().GetString("audit-redis-stream")
+
+	if dir == "" && connection == "" && stream == "" {
+		dir = viper.GetString("audit-dir")
+	}
+	if dir != "" {
+		return &mirrorcat.FileAuditSink{Dir: dir}, closeReader, nil
+	}
+
+	if stream == "" {
+		stream = viper.GetString("audit-redis-stream")
+	}
+	if stream == "" {
+		err = errors.New("no audit log is configured, see --audit-dir and --audit-redis-stream")
+		return
+	}
+
+	if connection != "" {
+		viper.Set("redis-sentinel-master", "")
+		viper.Set("redis-cluster-addrs", []string{})
+	} else {
+		connection = viper.GetString("redis-connection")
+	}
+
+	client, _, err := newRedisClient(connection)
+	if err != nil {
+		return
+	}
+	return mirrorcat.RedisAuditSink{UniversalClient: client, Stream: stream}, func() { client.Close() }, nil
+}
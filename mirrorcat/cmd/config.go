@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/Azure/mirrorcat"
+	"github.com/spf13/cobra"
+)
+
+// configCmd represents the config command
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Commands for inspecting MirrorCat configuration files.",
+}
+
+func init() {
+	RootCmd.AddCommand(configCmd)
+}
+
+// knownConfigKeys enumerates each top-level key that MirrorCat understands when it is found in a config file.
+var knownConfigKeys = map[string]struct{}{
+	"port":                       {},
+	"clone-depth":                {},
+	"transitive":                 {},
+	"transitive-depth":           {},
+	"merge-strategy":             {},
+	"merge-errors":               {},
+	"redis-connection":           {},
+	"redis-sentinel-master":      {},
+	"redis-sentinel-addrs":       {},
+	"redis-cluster-addrs":        {},
+	"redis-password":             {},
+	"redis-db":                   {},
+	"redis-tls":                  {},
+	"redis-tls-skip-verify":      {},
+	"redis-tls-ca-file":          {},
+	"redis-health-interval":      {},
+	"redis-failure-threshold":    {},
+	"cache-ttl":                  {},
+	"cache-negative-ttl":         {},
+	"cache-max-entries":          {},
+	"redis-watch":                {},
+	"redis-enable-notifications": {},
+	"redis-sync-on-add":          {},
+	"redis-json-prefixes":        {},
+	"github-auth-token":          {},
+	"github-auth-username":       {},
+	"hostname":                   {},
+	"mirrors":                    {},
+	"exclusions":                 {},
+	"static-priority":            {},
+	"redis-priority":             {},
+	"static-overrides":           {},
+	"redis-overrides":            {},
+	"mappings-dir":               {},
+	"mappings-dir-priority":      {},
+	"mappings-dir-overrides":     {},
+	"mapping-service-url":        {},
+	"mapping-service-token":      {},
+	"mapping-service-headers":    {},
+	"mapping-service-timeout":    {},
+	"mapping-service-retries":    {},
+	"mapping-service-priority":   {},
+	"mapping-service-overrides":  {},
+	"sql-driver":                 {},
+	"sql-dsn":                    {},
+	"sql-priority":               {},
+	"sql-overrides":              {},
+	"admin-token":                {},
+	"mappings-backend":           {},
+	"job-history":                {},
+	"audit-dir":                  {},
+	"audit-max-megabytes":        {},
+	"audit-max-files":            {},
+	"audit-redis-stream":         {},
+	"audit-redis-max-len":        {},
+	"min-free-megabytes":         {},
+	"shutdown-grace-period":      {},
+	"log-format":                 {},
+	"log-level":                  {},
+	"otlp-endpoint":              {},
+	"trace-sample-ratio":         {},
+}
+
+// parseMirrors interprets the contents of the `mirrors` or `exclusions` configuration properties. Any portion of the
+// property that is not in the expected format is skipped, and a description of it is returned in `skipped`.
+//
+// The expected format is a map of original repositories, to a map of original refs, to a map of
+// mirror repositories, to a list of mirror refs.
+func parseMirrors(raw interface{}) (mappings []mirrorcat.Mapping, skipped []error) {
+	originalRepos, ok := raw.(map[string]interface{})
+	if !ok {
+		skipped = append(skipped, fmt.Errorf("`mirrors` was in an unexpected format"))
+		return
+	}
+
+	for _, origRepo := range sortedKeys(originalRepos) {
+		originalRefs, ok := originalRepos[origRepo].(map[string]interface{})
+		if !ok {
+			skipped = append(skipped, fmt.Errorf("skipping because key %q was in an unexpected format", origRepo))
+			continue
+		}
+
+		for _, origRef := range sortedKeys(originalRefs) {
+			original := mirrorcat.RemoteRef{
+				Repository: origRepo,
+				Ref:        origRef,
+			}
+
+			remoteRepos, ok := originalRefs[origRef].(map[string]interface{})
+			if !ok {
+				skipped = append(skipped, fmt.Errorf("skipping because key %q was in an unexpected format", origRef))
+				continue
+			}
+
+			for _, remote := range sortedKeys(remoteRepos) {
+				remoteRefs, ok := remoteRepos[remote].([]interface{})
+				if !ok {
+					skipped = append(skipped, fmt.Errorf("skipping because key %q was in an unexpected format", remote))
+					continue
+				}
+
+				for _, remoteRef := range remoteRefs {
+					ref, ok := remoteRef.(string)
+					if !ok {
+						skipped = append(skipped, fmt.Errorf("skipping because %v, listed under %q, was not a string", remoteRef, remote))
+						continue
+					}
+
+					mappings = append(mappings, mirrorcat.Mapping{
+						Original: original,
+						Mirror: mirrorcat.RemoteRef{
+							Repository: remote,
+							Ref:        ref,
+						},
+					})
+				}
+			}
+		}
+	}
+	return
+}
+
+func sortedKeys(subject map[string]interface{}) []string {
+	keys := make([]string, 0, len(subject))
+	for key := range subject {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
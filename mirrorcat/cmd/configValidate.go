@@ -0,0 +1,290 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Azure/mirrorcat"
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// configValidateCmd represents the config validate command
+var configValidateCmd = &cobra.Command{
+	Use:   "validate [config file]",
+	Short: "Checks a config file for problems without starting MirrorCat.",
+	Long: `Loads a config file and interprets its "mirrors" property in the same way that
+"mirrorcat start" would. Any unknown keys, malformed repositories or refs, duplicate
+mappings, branches that mirror themselves, or cycles of mirrors are reported.
+
+If no config file is provided, the one that "mirrorcat start" would use is validated.
+The command exits with a non-zero status if any problems were found.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var cfgPath string
+		if len(args) > 0 {
+			cfgPath = args[0]
+		}
+
+		format, _ := cmd.Flags().GetString("output")
+		if format != "text" && format != "json" {
+			fmt.Fprintf(os.Stderr, "unrecognized output format %q\n", format)
+			os.Exit(1)
+		}
+
+		report, err := validateConfigFile(cfgPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+
+		if remote, _ := cmd.Flags().GetBool("remote"); remote {
+			timeout, _ := cmd.Flags().GetDuration("timeout")
+			report.checkRemotes(timeout)
+		}
+
+		if format == "json" {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			encoder.Encode(report)
+		} else {
+			fmt.Printf("%s: %d mappings, %d problems\n", report.File, len(report.Mappings), len(report.Problems))
+			for _, problem := range report.Problems {
+				fmt.Printf("\t[%s] %s\n", problem.Kind, problem.Message)
+			}
+		}
+
+		if len(report.Problems) > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configValidateCmd)
+
+	configValidateCmd.Flags().Bool("remote", false, "Contact every original and mirror repository with `git ls-remote` to ensure they are reachable.")
+	configValidateCmd.Flags().Duration("timeout", 30*time.Second, "The longest amount of time to wait for any single remote repository to respond.")
+	configValidateCmd.Flags().StringP("output", "o", "text", "The format of the report that is written. Either \"text\" or \"json\".")
+}
+
+// Kinds of problems that may be found while validating a config file.
+const (
+	problemUnknownKey  = "unknown-key"
+	problemFormat      = "format"
+	problemMalformed   = "malformed"
+	problemDuplicate   = "duplicate"
+	problemSelfMirror  = "self-mirror"
+	problemCycle       = "cycle"
+	problemUnreachable = "unreachable"
+)
+
+type validationProblem struct {
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+}
+
+type validationReport struct {
+	File     string              `json:"file"`
+	Mappings []mirrorcat.Mapping `json:"mappings"`
+	Problems []validationProblem `json:"problems"`
+}
+
+func (report *validationReport) add(kind, format string, a ...interface{}) {
+	report.Problems = append(report.Problems, validationProblem{
+		Kind:    kind,
+		Message: fmt.Sprintf(format, a...),
+	})
+}
+
+// validateConfigFile reads a config file without disturbing the configuration that is in use by
+// this process, and checks it for problems that do not require contacting any remote repository.
+func validateConfigFile(cfgPath string) (*validationReport, error) {
+	loader := viper.New()
+	if cfgPath == "" {
+		// By now, initConfig has resolved the root --config flag or the MIRRORCAT_CONFIG environment variable.
+		cfgPath = cfgFile
+	}
+
+	if cfgPath != "" {
+		loader.SetConfigFile(cfgPath)
+	} else {
+		home, err := homedir.Dir()
+		if err != nil {
+			return nil, err
+		}
+		loader.AddConfigPath(home)
+		loader.SetConfigName(".mirrorcat")
+	}
+
+	if err := loader.ReadInConfig(); err != nil {
+		return nil, err
+	}
+
+	report := &validationReport{
+		File:     loader.ConfigFileUsed(),
+		Mappings: []mirrorcat.Mapping{},
+		Problems: []validationProblem{},
+	}
+
+	for _, key := range sortedKeys(loader.AllSettings()) {
+		if _, ok := knownConfigKeys[key]; !ok {
+			report.add(problemUnknownKey, "%q is not a setting MirrorCat understands", key)
+		}
+	}
+
+	if !loader.InConfig("mirrors") {
+		report.add(problemFormat, "no `mirrors` property found")
+		return report, nil
+	}
+
+	mappings, skipped := parseMirrors(loader.Get("mirrors"))
+	for _, reason := range skipped {
+		report.add(problemFormat, "%v", reason)
+	}
+
+	// Viper lowercases every key that it reads, but repositories and refs are case sensitive. When the file is YAML or
+	// JSON, the mappings are read from it directly so that they are checked, and contacted, as they were written.
+	if preserved, err := readMirrorsPreservingCase(report.File); err == nil && len(skipped) == 0 {
+		mappings = preserved
+	}
+	if mappings != nil {
+		report.Mappings = mappings
+	}
+
+	if loader.InConfig("exclusions") {
+		exclusions, skipped := parseMirrors(loader.Get("exclusions"))
+		for _, reason := range skipped {
+			report.add(problemFormat, "exclusions: %v", reason)
+		}
+		for _, exclusion := range exclusions {
+			if err := exclusion.Original.Validate(); err != nil {
+				report.add(problemMalformed, "exclusions: %s", err)
+			}
+			if err := exclusion.Mirror.Validate(); err != nil {
+				report.add(problemMalformed, "exclusions: %s", err)
+			}
+		}
+	}
+
+	malformed := make(map[mirrorcat.RemoteRef]struct{})
+	checkWellFormed := func(subject mirrorcat.RemoteRef) {
+		if _, seen := malformed[subject]; seen {
+			return
+		}
+		if err := subject.Validate(); err != nil {
+			malformed[subject] = struct{}{}
+			report.add(problemMalformed, "%s", err)
+		}
+	}
+
+	seen := make(map[mirrorcat.Mapping]struct{}, len(mappings))
+	for _, mapping := range mappings {
+		checkWellFormed(mapping.Original)
+		checkWellFormed(mapping.Mirror)
+
+		if mapping.IsSelfMirror() {
+			report.add(problemSelfMirror, "%s would be mirrored onto itself", describeRemoteRef(mapping.Original))
+		}
+
+		key := mirrorcat.Mapping{
+			Original: mapping.Original.Canonical(),
+			Mirror:   mapping.Mirror.Canonical(),
+		}
+		if _, ok := seen[key]; ok {
+			report.add(problemDuplicate, "%s is mirrored to %s more than once", describeRemoteRef(mapping.Original), describeRemoteRef(mapping.Mirror))
+		}
+		seen[key] = struct{}{}
+	}
+
+	for _, cycle := range mirrorcat.FindCycles(mappings) {
+		members := make([]string, 0, len(cycle))
+		for _, member := range cycle {
+			members = append(members, describeRemoteRef(member))
+		}
+		report.add(problemCycle, "pushes would bounce between each of: %s", strings.Join(members, ", "))
+	}
+
+	return report, nil
+}
+
+// readMirrorsPreservingCase reads the `mirrors` property of a YAML or JSON config file without the help of viper.
+func readMirrorsPreservingCase(path string) ([]mirrorcat.Mapping, error) {
+	if !isYAMLOrJSON(path) {
+		return nil, fmt.Errorf("%s: only YAML and JSON files can be read without changing the case of their keys", path)
+	}
+	_, mappings, err := configMappingStore{path: path}.read()
+	return mappings, err
+}
+
+// isYAMLOrJSON determines, by its extension, whether a config file is written as YAML or JSON.
+func isYAMLOrJSON(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yml", ".yaml", ".json":
+		return true
+	default:
+		return false
+	}
+}
+
+// checkRemotes runs `git ls-remote` against each original and mirror in the report. Originals must
+// have the configured branch, while mirrors need only be reachable, because their branch will be created
+// by MirrorCat when it is first pushed.
+func (report *validationReport) checkRemotes(timeout time.Duration) {
+	originals := make(map[mirrorcat.RemoteRef]struct{})
+	mirrorRepos := make(map[string]struct{})
+	for _, mapping := range report.Mappings {
+		originals[mapping.Original] = struct{}{}
+		mirrorRepos[mapping.Mirror.Repository] = struct{}{}
+	}
+
+	targets := make([]mirrorcat.RemoteRef, 0, len(originals)+len(mirrorRepos))
+	for original := range originals {
+		targets = append(targets, original)
+	}
+	for repo := range mirrorRepos {
+		targets = append(targets, mirrorcat.RemoteRef{Repository: repo})
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		if targets[i].Repository != targets[j].Repository {
+			return targets[i].Repository < targets[j].Repository
+		}
+		return targets[i].Ref < targets[j].Ref
+	})
+
+	for _, target := range targets {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		err := mirrorcat.LsRemote(ctx, target)
+		cancel()
+		if err == nil {
+			continue
+		}
+
+		var reason string
+		if cmdErr, ok := err.(mirrorcat.CmdErr); ok {
+			reason = strings.TrimSpace(string(cmdErr.Output))
+		}
+		if reason == "" && target.Ref != "" {
+			reason = "no branch by that name exists"
+		} else if reason == "" {
+			reason = err.Error()
+		}
+
+		if target.Ref == "" {
+			report.add(problemUnreachable, "%s could not be reached: %s", target.Repository, reason)
+		} else {
+			report.add(problemUnreachable, "%s could not be found: %s", describeRemoteRef(target), reason)
+		}
+	}
+}
+
+func describeRemoteRef(subject mirrorcat.RemoteRef) string {
+	return fmt.Sprintf("%s (%s)", subject.Repository, subject.Ref)
+}
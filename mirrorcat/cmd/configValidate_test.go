@@ -0,0 +1,55 @@
+package cmd_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Azure/mirrorcat"
+)
+
+func TestConfigValidate_rootConfigFlag(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mirrorcat_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cfgPath := filepath.Join(dir, "mirrorcat.yml")
+	err = ioutil.WriteFile(cfgPath, []byte(`mirrors:
+  https://github.com/Azure/mirrorcat.git:
+    Release-2018:
+      https://github.com/marstr/MirrorCat.git:
+        - Release-2018
+`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, stderr, err := runMirrorCat("--config", cfgPath, "config", "validate", "--output", "json")
+	if err != nil {
+		t.Fatalf("%v: %s", err, stderr)
+	}
+
+	var report struct {
+		File     string              `json:"file"`
+		Mappings []mirrorcat.Mapping `json:"mappings"`
+	}
+	if err = json.Unmarshal([]byte(stdout), &report); err != nil {
+		t.Fatalf("%v: %s", err, stdout)
+	}
+
+	if report.File != cfgPath {
+		t.Errorf("got: %q want: %q", report.File, cfgPath)
+	}
+
+	want := mirrorcat.Mapping{
+		Original: mirrorcat.RemoteRef{Repository: "https://github.com/Azure/mirrorcat.git", Ref: "Release-2018"},
+		Mirror:   mirrorcat.RemoteRef{Repository: "https://github.com/marstr/MirrorCat.git", Ref: "Release-2018"},
+	}
+	if len(report.Mappings) != 1 || report.Mappings[0] != want {
+		t.Errorf("got: %v want: %v", report.Mappings, []mirrorcat.Mapping{want})
+	}
+}
@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azure/mirrorcat"
+)
+
+// DefaultJobHistory is the number of push jobs that MirrorCat remembers for the /v1/jobs API, if not specified by the
+// invoker of MirrorCat.
+const DefaultJobHistory = 1000
+
+// DefaultJobsPageSize and MaxJobsPageSize bound the number of jobs returned by a single request to /v1/jobs.
+const (
+	DefaultJobsPageSize = 50
+	MaxJobsPageSize     = 500
+)
+
+// jobHistory records each attempt to push to a mirror, whether it was triggered by a push event or by a mirror being
+// added to Redis.
+var jobHistory = mirrorcat.NewJobHistory(DefaultJobHistory)
+
+// jobsPage is a single page of the jobs matching a request to /v1/jobs.
+type jobsPage struct {
+	Jobs  []mirrorcat.Job `json:"jobs"`
+	Total int             `json:"total"`
+	Next  string          `json:"next,omitempty"`
+}
+
+// handleJobs lists the jobs which match the "repo", "ref", "status", "since", and "until" query parameters, most
+// recent first. The "offset" and "limit" query parameters page through them. A single job is found by its ID at
+// "/v1/jobs/{id}".
+func handleJobs(resp http.ResponseWriter, req *http.Request) {
+	if !authorizeReader(resp, req) {
+		return
+	}
+
+	if req.Method != http.MethodGet {
+		resp.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if id := strings.Trim(strings.TrimPrefix(req.URL.Path, "/v1/jobs"), "/"); id != "" {
+		job, ok := jobHistory.Get(id)
+		if !ok {
+			http.Error(resp, "no such job", http.StatusNotFound)
+			return
+		}
+		resp.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(resp).Encode(job)
+		return
+	}
+
+	query := req.URL.Query()
+	filter, err := parseJobFilter(query)
+	if err != nil {
+		http.Error(resp, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	offset, err := nonNegativeParam(query, "offset", 0)
+	if err != nil {
+		http.Error(resp, err.Error(), http.StatusBadRequest)
+		return
+	}
+	limit, err := nonNegativeParam(query, "limit", DefaultJobsPageSize)
+	if err != nil {
+		http.Error(resp, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if limit == 0 || limit > MaxJobsPageSize {
+		limit = MaxJobsPageSize
+	}
+
+	var page jobsPage
+	page.Jobs, page.Total = jobHistory.Query(filter, offset, limit)
+	if next := offset + len(page.Jobs); next < page.Total {
+		query.Set("offset", strconv.Itoa(next))
+		query.Set("limit", strconv.Itoa(limit))
+		page.Next = "/v1/jobs?" + query.Encode()
+	}
+
+	resp.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(resp).Encode(page)
+}
+
+// parseJobFilter reads the query parameters which select jobs from /v1/jobs.
+func parseJobFilter(query url.Values) (filter mirrorcat.JobFilter, err error) {
+	filter.Repository = query.Get("repo")
+	filter.Ref = query.Get("ref")
+
+	if raw := query.Get("status"); raw != "" {
+		var status mirrorcat.JobStatus
+		if status, err = mirrorcat.ParseJobStatus(raw); err != nil {
+			return
+		}
+		filter.Status = &status
+	}
+
+	if raw := query.Get("since"); raw != "" {
+		if filter.Since, err = time.Parse(time.RFC3339, raw); err != nil {
+			err = fmt.Errorf("since must be an RFC 3339 time: %v", err)
+			return
+		}
+	}
+
+	if raw := query.Get("until"); raw != "" {
+		if filter.Until, err = time.Parse(time.RFC3339, raw); err != nil {
+			err = fmt.Errorf("until must be an RFC 3339 time: %v", err)
+			return
+		}
+	}
+	return
+}
+
+// nonNegativeParam reads an optional query parameter holding a count.
+func nonNegativeParam(query url.Values, name string, fallback int) (int, error) {
+	raw := query.Get(name)
+	if raw == "" {
+		return fallback, nil
+	}
+
+	value, err := strconv.Atoi(raw)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative integer", name)
+	}
+	return value, nil
+}
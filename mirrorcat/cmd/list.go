@@ -0,0 +1,200 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Azure/mirrorcat"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// listCmd represents the list command
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Prints every mapping that MirrorCat knows about.",
+	Long: `Prints every mapping found in each source that "mirrorcat start" would use: the
+config file, --mappings-dir, Redis, and a SQL database. A mapping service is only able
+to answer for a single original, so its mappings can't be listed.
+
+Each mapping is printed alongside the source that it was found in, so the same mapping
+may be printed more than once.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		format, _ := cmd.Flags().GetString("output")
+		if format != "table" && format != "json" {
+			fmt.Fprintf(os.Stderr, "unrecognized output format %q\n", format)
+			os.Exit(1)
+		}
+
+		exclusions, _ := cmd.Flags().GetBool("exclusions")
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		sources, closeSources, err := listableSources(ctx, cmd)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+		defer closeSources()
+
+		listed, err := collectSourcedMappings(ctx, sources, exclusions)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+
+		printSourcedMappings(format, listed)
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(listCmd)
+
+	addSourceFlags(listCmd)
+	listCmd.Flags().Bool("exclusions", false, "Print exclusions, rather than mappings.")
+	listCmd.Flags().Duration("timeout", time.Minute, "The longest amount of time to spend reading from all sources.")
+	listCmd.Flags().StringP("output", "o", "table", "The format of the list that is written. Either \"table\" or \"json\".")
+}
+
+// sourcedMapping is a single entry printed by "mirrorcat list".
+type sourcedMapping struct {
+	Source string `json:"source"`
+	mirrorcat.Mapping
+}
+
+// collectSourcedMappings gathers every mapping, or exclusion, from each source. Sources which are unable to
+// enumerate their exclusions are skipped when `exclusions` is set.
+func collectSourcedMappings(ctx context.Context, sources []namedLister, exclusions bool) ([]sourcedMapping, error) {
+	listed := []sourcedMapping{}
+	for _, source := range sources {
+		lister := source.MirrorLister
+		if exclusions {
+			excluder, ok := lister.(exclusionSource)
+			if !ok {
+				continue
+			}
+			lister = exclusionLister{excluder}
+		}
+
+		found, err := mirrorcat.CollectMappings(ctx, lister)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list mappings from %s: %v", source.name, err)
+		}
+
+		for _, m := range found {
+			listed = append(listed, sourcedMapping{Source: source.name, Mapping: m})
+		}
+	}
+	return listed, nil
+}
+
+// printSourcedMappings writes mappings to stdout, either as a table or as JSON.
+func printSourcedMappings(format string, listed []sourcedMapping) {
+	if format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(listed)
+		return
+	}
+
+	table := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(table, "SOURCE\tORIGINAL\tORIGINAL REF\tMIRROR\tMIRROR REF")
+	for _, m := range listed {
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\n", m.Source, m.Original.Repository, m.Original.Ref, m.Mirror.Repository, m.Mirror.Ref)
+	}
+	table.Flush()
+}
+
+// namedLister is a source of mappings, along with the name it is configured by.
+type namedLister struct {
+	mirrorcat.MirrorLister
+	name string
+}
+
+// addSourceFlags allows the sources opened by listableSources to be chosen on the command line.
+func addSourceFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("redis-connection", "r", "", "The URL of a single Redis server to read. Defaults to the same Redis deployment \"mirrorcat start\" would use.")
+	cmd.Flags().String("mappings-dir", "", "A directory of mapping files to read. Defaults to the same directory \"mirrorcat start\" would use.")
+	cmd.Flags().String("sql-driver", "", "The driver used to open --sql-dsn, either \"postgres\" or \"sqlite3\". Defaults to the same driver \"mirrorcat start\" would use.")
+	cmd.Flags().String("sql-dsn", "", "A database to read. Defaults to the same database \"mirrorcat start\" would use.")
+}
+
+// listableSources opens each source of mappings that "mirrorcat start" would use and that is able to enumerate its
+// mappings, unless the flags added by addSourceFlags say otherwise. The returned function releases any connections
+// that were opened.
+func listableSources(ctx context.Context, cmd *cobra.Command) (sources []namedLister, closeSources func(), err error) {
+	var closers []func() error
+	closeSources = func() {
+		for _, c := range closers {
+			c()
+		}
+	}
+
+	flagOrSetting := func(name string) string {
+		if value, _ := cmd.Flags().GetString(name); value != "" {
+			return value
+		}
+		return viper.GetString(name)
+	}
+
+	if viper.InConfig("mirrors") {
+		static := mirrorcat.NewDefaultMirrorFinder()
+		mappings, skipped := parseMirrors(viper.Get("mirrors"))
+		for _, reason := range skipped {
+			fmt.Fprintln(os.Stderr, reason)
+		}
+		for _, m := range mappings {
+			static.AddMirrors(m.Original, m.Mirror)
+		}
+		if viper.InConfig("exclusions") {
+			exclusions, _ := parseMirrors(viper.Get("exclusions"))
+			for _, m := range exclusions {
+				static.AddExclusions(m.Original, m.Mirror)
+			}
+		}
+		sources = append(sources, namedLister{MirrorLister: static, name: "static"})
+	}
+
+	if dir := flagOrSetting("mappings-dir"); dir != "" {
+		finder := mirrorcat.NewDirectoryFinder(dir)
+		for _, err := range finder.Load() {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		sources = append(sources, namedLister{MirrorLister: finder, name: "mappings-dir"})
+	}
+
+	connection, _ := cmd.Flags().GetString("redis-connection")
+	if connection != "" {
+		viper.Set("redis-sentinel-master", "")
+		viper.Set("redis-cluster-addrs", []string{})
+	} else {
+		connection = viper.GetString("redis-connection")
+	}
+	if connection != "" || viper.GetString("redis-sentinel-master") != "" || len(listSetting("redis-cluster-addrs")) > 0 {
+		client, _, err := newRedisClient(connection)
+		if err != nil {
+			closeSources()
+			return nil, nil, err
+		}
+		closers = append(closers, client.Close)
+		sources = append(sources, namedLister{MirrorLister: mirrorcat.RedisFinder{UniversalClient: client}, name: "redis"})
+	}
+
+	if dsn := flagOrSetting("sql-dsn"); dsn != "" {
+		db, _, err := openSQL(ctx, flagOrSetting("sql-driver"), dsn)
+		if err != nil {
+			closeSources()
+			return nil, nil, err
+		}
+		closers = append(closers, db.Close)
+		sources = append(sources, namedLister{MirrorLister: mirrorcat.SQLFinder{DB: db}, name: "sql"})
+	}
+
+	return
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"github.com/Azure/mirrorcat"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// DefaultLogFormat and DefaultLogLevel describe how MirrorCat logs, if not specified by the invoker of MirrorCat.
+const (
+	DefaultLogFormat = "logfmt"
+	DefaultLogLevel  = "info"
+)
+
+// configureLogging applies the "log-format" and "log-level" settings, and identifies `host` in every entry.
+func configureLogging(host string) error {
+	formatter, err := mirrorcat.ParseLogFormat(viper.GetString("log-format"))
+	if err != nil {
+		return err
+	}
+
+	level, err := logrus.ParseLevel(viper.GetString("log-level"))
+	if err != nil {
+		return err
+	}
+
+	logrus.SetFormatter(formatter)
+	logrus.SetLevel(level)
+	mirrorcat.Logger = logrus.WithField("host", host)
+	return nil
+}
+
+// logRefs describes each of a list of RemoteRefs for use as the value of a log field.
+func logRefs(refs []mirrorcat.RemoteRef) []string {
+	described := make([]string, len(refs))
+	for i, ref := range refs {
+		described[i] = mirrorcat.LogRef(ref)
+	}
+	return described
+}
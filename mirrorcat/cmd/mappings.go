@@ -0,0 +1,555 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Azure/mirrorcat"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	yaml "go.yaml.in/yaml/v3"
+)
+
+// mappingStore is a source of mappings which the "/v1/mappings" API is able to change.
+type mappingStore interface {
+	mirrorcat.MirrorLister
+
+	// add stores a mapping, replacing its options if it was already stored.
+	add(ctx context.Context, m mirrorcat.Mapping, options mirrorcat.MirrorOptions) error
+
+	// remove deletes a mapping. If it was not stored, `found` is false.
+	remove(ctx context.Context, m mirrorcat.Mapping) (found bool, err error)
+}
+
+// errOptionsUnsupported is returned when options are provided for a mapping, but the store can't hold them.
+var errOptionsUnsupported = errors.New("options can only be stored alongside mappings in Redis")
+
+// mappingsAPI holds the store that is changed by the "/v1/mappings" API, chosen by the "mappings-backend" setting.
+// Requests are handled one at a time, so that the ETag each request is checked against can't change while it is
+// being handled.
+var mappingsAPI struct {
+	sync.Mutex
+	store mappingStore
+}
+
+// authorizeAdmin checks that a request to one of the APIs which change MirrorCat's state carries "admin-token" as its
+// bearer token. These APIs are disabled when no token has been configured. If the request isn't allowed, an error is
+// written to `resp` and false is returned.
+func authorizeAdmin(resp http.ResponseWriter, req *http.Request, api string) bool {
+	token := viper.GetString("admin-token")
+	if token == "" {
+		http.Error(resp, fmt.Sprintf("the %s API is disabled, see --admin-token", api), http.StatusNotFound)
+		return false
+	}
+	return checkBearer(resp, req, token)
+}
+
+// authorizeReader checks that a request to one of the read-only APIs carries "admin-token" as its bearer token, if one
+// has been configured. Without a token, these APIs stay open to anyone who is able to reach MirrorCat.
+func authorizeReader(resp http.ResponseWriter, req *http.Request) bool {
+	token := viper.GetString("admin-token")
+	if token == "" {
+		return true
+	}
+	return checkBearer(resp, req, token)
+}
+
+// checkBearer compares the bearer token of a request to `token`, writing an error to `resp` if they don't match. The
+// token must be sent using the "Bearer" authentication scheme, whose name isn't case sensitive.
+func checkBearer(resp http.ResponseWriter, req *http.Request, token string) bool {
+	const scheme = "Bearer "
+
+	var provided string
+	if header := req.Header.Get("Authorization"); len(header) > len(scheme) && strings.EqualFold(header[:len(scheme)], scheme) {
+		provided = header[len(scheme):]
+	}
+
+	if provided == "" || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+		resp.Header().Set("WWW-Authenticate", `Bearer realm="mirrorcat"`)
+		http.Error(resp, "a valid bearer token is required", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+// mappingRequest is the body of a request to create a mapping.
+type mappingRequest struct {
+	mirrorcat.Mapping
+	Options *mirrorcat.MirrorOptions `json:"options,omitempty"`
+}
+
+// handleMappings allows the mappings in a writable backend to be listed, read, created, and deleted at runtime.
+//
+// A GET lists every mapping, only those of the original in the "repo" and "ref" query parameters, or a single mapping
+// if "mirror-repo" and "mirror-ref" are also provided. A POST creates the mapping in its body, and a DELETE removes
+// the mapping described by all four query parameters.
+//
+// Every response carries an ETag describing the mappings that were stored when the request was handled. A POST or
+// DELETE must provide it as "If-Match", so that a change made by someone else since it was read is not overwritten.
+func handleMappings(resp http.ResponseWriter, req *http.Request) {
+	if !authorizeAdmin(resp, req, "mappings") {
+		return
+	}
+
+	mappingsAPI.Lock()
+	defer mappingsAPI.Unlock()
+
+	store := mappingsAPI.store
+	if store == nil {
+		http.Error(resp, "no writable backend is configured, see --mappings-backend", http.StatusServiceUnavailable)
+		return
+	}
+
+	current, err := mirrorcat.CollectMappings(req.Context(), store)
+	if err != nil {
+		mirrorcat.Logger.WithError(err).Error("Unable to list stored mappings")
+		http.Error(resp, "unable to list stored mappings", http.StatusBadGateway)
+		return
+	}
+	etag := mappingsETag(current)
+	resp.Header().Set("ETag", etag)
+
+	query := req.URL.Query()
+	switch req.Method {
+	case http.MethodGet:
+		found := []mirrorcat.Mapping{}
+		for _, m := range current {
+			if query.Get("repo") != "" && m.Original.Repository != query.Get("repo") {
+				continue
+			}
+			if query.Get("ref") != "" && m.Original.Ref != mirrorcat.NormalizeRef(query.Get("ref")) {
+				continue
+			}
+			if query.Get("mirror-repo") != "" && m.Mirror.Repository != query.Get("mirror-repo") {
+				continue
+			}
+			if query.Get("mirror-ref") != "" && m.Mirror.Ref != mirrorcat.NormalizeRef(query.Get("mirror-ref")) {
+				continue
+			}
+			found = append(found, m)
+		}
+
+		resp.Header().Set("Content-Type", "application/json")
+		if query.Get("mirror-repo") != "" && query.Get("mirror-ref") != "" {
+			if len(found) == 0 {
+				http.Error(resp, "no such mapping", http.StatusNotFound)
+				return
+			}
+			json.NewEncoder(resp).Encode(found[0])
+			return
+		}
+		json.NewEncoder(resp).Encode(found)
+
+	case http.MethodPost:
+		if !checkIfMatch(resp, req, etag) {
+			return
+		}
+
+		var body mappingRequest
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			http.Error(resp, err.Error(), http.StatusBadRequest)
+			return
+		}
+		m := body.Mapping
+		m.Original.Ref = mirrorcat.NormalizeRef(m.Original.Ref)
+		m.Mirror.Ref = mirrorcat.NormalizeRef(m.Mirror.Ref)
+		if err := validateMapping(m); err != nil {
+			http.Error(resp, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var options mirrorcat.MirrorOptions
+		if body.Options != nil {
+			options = *body.Options
+		}
+
+		if err := store.add(req.Context(), m, options); err == errOptionsUnsupported {
+			http.Error(resp, err.Error(), http.StatusBadRequest)
+			return
+		} else if err != nil {
+			mappingLogger(m).WithError(err).Error("Unable to store mapping")
+			http.Error(resp, "unable to store mapping", http.StatusBadGateway)
+			return
+		}
+
+		mappingLogger(m).Info("Stored mapping")
+		mappingsChanged(req.Context(), resp, store, m.Original)
+		resp.Header().Set("Content-Type", "application/json")
+		resp.WriteHeader(http.StatusCreated)
+		json.NewEncoder(resp).Encode(m)
+
+	case http.MethodDelete:
+		if !checkIfMatch(resp, req, etag) {
+			return
+		}
+
+		m := mirrorcat.Mapping{
+			Original: mirrorcat.RemoteRef{Repository: query.Get("repo"), Ref: mirrorcat.NormalizeRef(query.Get("ref"))},
+			Mirror:   mirrorcat.RemoteRef{Repository: query.Get("mirror-repo"), Ref: mirrorcat.NormalizeRef(query.Get("mirror-ref"))},
+		}
+		if err := validateMapping(m); err != nil {
+			http.Error(resp, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		found, err := store.remove(req.Context(), m)
+		if err != nil {
+			mappingLogger(m).WithError(err).Error("Unable to delete mapping")
+			http.Error(resp, "unable to delete mapping", http.StatusBadGateway)
+			return
+		}
+		if !found {
+			http.Error(resp, "no such mapping", http.StatusNotFound)
+			return
+		}
+
+		mappingLogger(m).Info("Deleted mapping")
+		mappingsChanged(req.Context(), resp, store, m.Original)
+		resp.WriteHeader(http.StatusNoContent)
+
+	default:
+		resp.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// checkIfMatch ensures that a request which changes mappings was made with knowledge of the current mappings. If it
+// was not, an error is written to `resp`.
+func checkIfMatch(resp http.ResponseWriter, req *http.Request, etag string) bool {
+	match := req.Header.Get("If-Match")
+	switch {
+	case match == "":
+		http.Error(resp, "an If-Match header is required", http.StatusPreconditionRequired)
+		return false
+	case match != "*" && match != etag:
+		http.Error(resp, "the mappings have changed since they were read", http.StatusPreconditionFailed)
+		return false
+	}
+	return true
+}
+
+// mappingsChanged forgets any cached results about `original`, checks the new mappings for cycles, and reports the
+// new ETag in `resp`.
+func mappingsChanged(ctx context.Context, resp http.ResponseWriter, store mappingStore, original mirrorcat.RemoteRef) {
+	invalidateCaches(&original)
+	analyzeMirrors()
+
+	if current, err := mirrorcat.CollectMappings(ctx, store); err == nil {
+		resp.Header().Set("ETag", mappingsETag(current))
+	} else {
+		resp.Header().Del("ETag")
+	}
+}
+
+// mappingLogger describes a mapping being changed through /v1/mappings in each log entry about it.
+func mappingLogger(m mirrorcat.Mapping) *logrus.Entry {
+	return mirrorcat.Logger.WithFields(logrus.Fields{
+		mirrorcat.LogFieldOriginal: mirrorcat.LogRef(m.Original),
+		mirrorcat.LogFieldMirror:   mirrorcat.LogRef(m.Mirror),
+	})
+}
+
+// mappingsETag summarizes a set of mappings, regardless of the order that they were found in.
+func mappingsETag(mappings []mirrorcat.Mapping) string {
+	lines := make([]string, 0, len(mappings))
+	for _, m := range mappings {
+		lines = append(lines, fmt.Sprintf("%q %q %q %q", m.Original.Repository, m.Original.Ref, m.Mirror.Repository, m.Mirror.Ref))
+	}
+	sort.Strings(lines)
+
+	hash := sha256.New()
+	for _, line := range lines {
+		fmt.Fprintln(hash, line)
+	}
+	return fmt.Sprintf(`"%x"`, hash.Sum(nil)[:16])
+}
+
+// redisMappingStore changes the mappings stored in Redis.
+type redisMappingStore struct {
+	mirrorcat.RedisFinder
+	jsonPrefixes []string
+}
+
+func (rs redisMappingStore) add(ctx context.Context, m mirrorcat.Mapping, options mirrorcat.MirrorOptions) error {
+	enc := mirrorcat.RedisEncodingFor(m.Original, rs.jsonPrefixes)
+	for _, target := range []mirrorcat.RemoteRef{m.Original, m.Mirror} {
+		if strings.ContainsAny(target.Ref, ": ") || strings.Contains(target.Repository, " ") {
+			enc = mirrorcat.RedisJSONEncoding
+		}
+	}
+	return mirrorcat.SaveRedisMapping(rs.UniversalClient, m, options, enc)
+}
+
+func (rs redisMappingStore) remove(ctx context.Context, m mirrorcat.Mapping) (found bool, err error) {
+	if _, found, err = mirrorcat.ReadRedisOptions(rs.UniversalClient, m); err != nil {
+		return
+	}
+	if found {
+		if err = mirrorcat.DeleteRedisMapping(rs.UniversalClient, m); err != nil {
+			return
+		}
+	}
+
+	legacy, err := removeRedisMembers(rs.UniversalClient, "", m)
+	found = found || legacy > 0
+	return
+}
+
+// configMappingStore changes the `mirrors` property of a YAML or JSON config file, leaving the rest of it, including
+// any comments in a YAML file, in place. After each change, the static mirrors that are in use are read again.
+type configMappingStore struct {
+	path string
+}
+
+// configFilePath finds the config file in use, or the one named by the environment variable "MIRRORCAT_CONFIG".
+func configFilePath() string {
+	if used := viper.ConfigFileUsed(); used != "" {
+		return used
+	}
+	return os.Getenv("MIRRORCAT_CONFIG")
+}
+
+func (cs configMappingStore) ListMirrors(ctx context.Context, results chan<- mirrorcat.Mapping) error {
+	_, mappings, err := cs.read()
+	if err != nil {
+		close(results)
+		return err
+	}
+
+	found := mirrorcat.NewDefaultMirrorFinder()
+	for _, m := range mappings {
+		found.AddMirrors(m.Original, m.Mirror)
+	}
+	return found.ListMirrors(ctx, results)
+}
+
+func (cs configMappingStore) add(ctx context.Context, m mirrorcat.Mapping, options mirrorcat.MirrorOptions) error {
+	if options != (mirrorcat.MirrorOptions{}) {
+		return errOptionsUnsupported
+	}
+
+	doc, mappings, err := cs.read()
+	if err != nil {
+		return err
+	}
+
+	for _, existing := range mappings {
+		if existing == m {
+			return nil
+		}
+	}
+
+	if err = cs.write(doc, append(mappings, m)); err != nil {
+		return err
+	}
+	return populateStaticMirrors()
+}
+
+func (cs configMappingStore) remove(ctx context.Context, m mirrorcat.Mapping) (found bool, err error) {
+	doc, mappings, err := cs.read()
+	if err != nil {
+		return
+	}
+
+	remaining := make([]mirrorcat.Mapping, 0, len(mappings))
+	for _, existing := range mappings {
+		if existing == m {
+			found = true
+			continue
+		}
+		remaining = append(remaining, existing)
+	}
+	if !found {
+		return
+	}
+
+	if err = cs.write(doc, remaining); err != nil {
+		return
+	}
+	err = populateStaticMirrors()
+	return
+}
+
+// isJSON determines whether the config file is written as JSON, rather than YAML.
+func (cs configMappingStore) isJSON() bool {
+	return strings.ToLower(filepath.Ext(cs.path)) == ".json"
+}
+
+// read parses the whole config file, along with the mappings in its `mirrors` property. The returned document is
+// either a `*yaml.Node` or a `map[string]interface{}`, which preserve the contents of the rest of the file.
+func (cs configMappingStore) read() (doc interface{}, mappings []mirrorcat.Mapping, err error) {
+	doc, raw, err := cs.parse()
+	if err != nil {
+		return
+	}
+
+	mappings, skipped := cs.mappingsIn(raw, "mirrors")
+	if len(skipped) > 0 {
+		return nil, nil, fmt.Errorf("%s: %v", cs.path, skipped[0])
+	}
+	return
+}
+
+// parse reads the whole config file, both as a document which can be written back by `write`, and as plain values.
+func (cs configMappingStore) parse() (doc interface{}, raw map[string]interface{}, err error) {
+	contents, err := ioutil.ReadFile(cs.path)
+	if err != nil {
+		return
+	}
+
+	if cs.isJSON() {
+		if err = json.Unmarshal(contents, &raw); err != nil {
+			return
+		}
+		return raw, raw, nil
+	}
+
+	var root yaml.Node
+	if err = yaml.Unmarshal(contents, &root); err != nil {
+		return
+	}
+	if root.Kind != 0 {
+		if err = root.Decode(&raw); err != nil {
+			return
+		}
+	}
+	return &root, raw, nil
+}
+
+// mappingsIn reads the mappings held by a property of a config file that has been parsed, such as `mirrors` or
+// `exclusions`, along with the reason that any entries were skipped.
+func (cs configMappingStore) mappingsIn(raw map[string]interface{}, property string) (mappings []mirrorcat.Mapping, skipped []error) {
+	if rawMappings, ok := raw[property]; ok {
+		mappings, skipped = parseMirrors(stringKeyed(rawMappings))
+	}
+	return
+}
+
+// write replaces the `mirrors` property of a document returned by `read`, then replaces the config file with it.
+func (cs configMappingStore) write(doc interface{}, mappings []mirrorcat.Mapping) error {
+	tree := newMappingTree(mappings)
+	if tree == nil {
+		tree = mappingTree{}
+	}
+
+	var marshaled []byte
+	var err error
+	switch typed := doc.(type) {
+	case map[string]interface{}:
+		if typed == nil {
+			typed = make(map[string]interface{})
+		}
+		typed["mirrors"] = tree
+		marshaled, err = json.MarshalIndent(typed, "", "  ")
+	case *yaml.Node:
+		marshaled, err = replaceYAMLMirrors(typed, tree)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Writing to a temporary file, then renaming it, ensures that the config file is never seen half-written.
+	info, err := os.Stat(cs.path)
+	if err != nil {
+		return err
+	}
+
+	temp, err := ioutil.TempFile(filepath.Dir(cs.path), "."+filepath.Base(cs.path))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+
+	if _, err = temp.Write(marshaled); err != nil {
+		temp.Close()
+		return err
+	}
+	if err = temp.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(temp.Name(), info.Mode()); err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), cs.path)
+}
+
+// replaceYAMLMirrors sets the `mirrors` property of a YAML document to `tree`, and marshals it. Comments elsewhere in the
+// document are kept, as are those attached to the parts of `mirrors` which are still present.
+func replaceYAMLMirrors(root *yaml.Node, tree mappingTree) ([]byte, error) {
+	if root.Kind == 0 {
+		root.Kind = yaml.DocumentNode
+		root.Content = []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}
+	}
+
+	if root.Kind != yaml.DocumentNode || len(root.Content) != 1 || root.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.New("the config file does not hold a mapping")
+	}
+	body := root.Content[0]
+
+	var replacement yaml.Node
+	if err := replacement.Encode(tree); err != nil {
+		return nil, err
+	}
+
+	replaced := false
+	for i := 0; i+1 < len(body.Content); i += 2 {
+		if body.Content[i].Value == "mirrors" {
+			keepYAMLComments(body.Content[i+1], &replacement)
+			body.Content[i+1] = &replacement
+			replaced = true
+		}
+	}
+	if !replaced {
+		body.Content = append(body.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "mirrors"}, &replacement)
+	}
+
+	var marshaled bytes.Buffer
+	encoder := yaml.NewEncoder(&marshaled)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(root); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return marshaled.Bytes(), nil
+}
+
+// keepYAMLComments copies the comments attached to `previous` onto `next`, along with those attached to each of
+// its keys and items that `next` also has.
+func keepYAMLComments(previous, next *yaml.Node) {
+	next.HeadComment, next.LineComment, next.FootComment = previous.HeadComment, previous.LineComment, previous.FootComment
+
+	switch {
+	case previous.Kind == yaml.MappingNode && next.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(next.Content); i += 2 {
+			for j := 0; j+1 < len(previous.Content); j += 2 {
+				if previous.Content[j].Value == next.Content[i].Value {
+					keepYAMLComments(previous.Content[j], next.Content[i])
+					keepYAMLComments(previous.Content[j+1], next.Content[i+1])
+					break
+				}
+			}
+		}
+	case previous.Kind == yaml.SequenceNode && next.Kind == yaml.SequenceNode:
+		for _, item := range next.Content {
+			for _, old := range previous.Content {
+				if old.Value == item.Value {
+					keepYAMLComments(old, item)
+					break
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package cmd_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/mirrorcat"
+)
+
+// startMirrorCat runs "mirrorcat start" with `args` in a separate process, and waits for it to answer on `port`.
+func startMirrorCat(t *testing.T, port int, args ...string) (stop func()) {
+	t.Helper()
+
+	process := exec.Command(os.Args[0], "-test.run=^TestMirrorCatProcess$")
+	process.Env = append(os.Environ(), processArgsVariable+"="+strings.Join(args, "\n"))
+	if err := process.Start(); err != nil {
+		t.Fatal(err)
+	}
+	stop = func() {
+		process.Process.Kill()
+		process.Wait()
+	}
+
+	for deadline := time.Now().Add(10 * time.Second); time.Now().Before(deadline); time.Sleep(50 * time.Millisecond) {
+		if resp, err := http.Get(fmt.Sprintf("http://localhost:%d/healthz", port)); err == nil {
+			resp.Body.Close()
+			return
+		}
+	}
+	stop()
+	t.Fatal("mirrorcat start never answered")
+	return
+}
+
+// freePort finds a port that nothing is currently listening on.
+func freePort(t *testing.T) int {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func TestMappings_configFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mirrorcat_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	port := freePort(t)
+	cfgPath := filepath.Join(dir, "mirrorcat.yml")
+	err = ioutil.WriteFile(cfgPath, []byte(fmt.Sprintf(`# Kept by the release team.
+port: %d
+mirrors:
+  # The canonical repository.
+  https://github.com/Azure/MirrorCat.git:
+    master:
+      https://github.com/marstr/MirrorCat.git:
+        - master # Kept in sync for testing.
+`, port)), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stop := startMirrorCat(t, port, "--config", cfgPath, "start", "--admin-token", "secret")
+	defer stop()
+
+	base := fmt.Sprintf("http://localhost:%d", port)
+	send := func(method, path, authorization, body string) *http.Response {
+		t.Helper()
+
+		req, err := http.NewRequest(method, base+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", authorization)
+		req.Header.Set("If-Match", "*")
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	for _, authorization := range []string{"secret", "Basic secret", "Bearer wrong"} {
+		resp := send(http.MethodGet, "/v1/mappings", authorization, "")
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("%q got: %d want: %d", authorization, resp.StatusCode, http.StatusUnauthorized)
+		}
+	}
+
+	original := mirrorcat.RemoteRef{Repository: "https://github.com/Azure/MirrorCat.git", Ref: "master"}
+	added := mirrorcat.RemoteRef{Repository: "https://github.com/Other/Fork.git", Ref: "Dev"}
+	body, _ := json.Marshal(mirrorcat.Mapping{Original: original, Mirror: added})
+
+	resp := send(http.MethodPost, "/v1/mappings", "bearer secret", string(body))
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("got: %d want: %d", resp.StatusCode, http.StatusCreated)
+	}
+
+	written, err := ioutil.ReadFile(cfgPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, comment := range []string{"# Kept by the release team.", "# The canonical repository.", "# Kept in sync for testing.", "https://github.com/Other/Fork.git"} {
+		if !strings.Contains(string(written), comment) {
+			t.Errorf("%q is missing from the config file:\n%s", comment, written)
+		}
+	}
+
+	// The static mirrors in use are read again, keeping the case of each repository and ref.
+	query := url.Values{"repo": {added.Repository}, "ref": {added.Ref}}
+	resp = send(http.MethodGet, "/v1/originals?"+query.Encode(), "Bearer secret", "")
+	defer resp.Body.Close()
+
+	var report struct {
+		Originals []mirrorcat.RemoteRef `json:"originals"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Originals) != 1 || report.Originals[0] != original {
+		t.Errorf("got: %v want: %v", report.Originals, []mirrorcat.RemoteRef{original})
+	}
+}
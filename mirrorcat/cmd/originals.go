@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Azure/mirrorcat"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// originalsCmd represents the originals command
+var originalsCmd = &cobra.Command{
+	Use:   "originals {mirror repository} {mirror ref}",
+	Short: "Prints the mappings that push onto a mirror.",
+	Long: `Finds each original which is pushed onto a mirror, which helps to explain why a branch
+was overwritten. Every source that "mirrorcat list" reads is consulted, and each mapping
+that pushes onto the mirror is printed alongside the source that it was found in. Mappings
+that "mirrorcat start" wouldn't push along, because they are excluded, overridden, or would
+push in circles, are left out.
+
+When "mirrorcat start" would push transitively, the mappings which push onto each of
+those originals are also printed, and so on, up to --transitive-depth hops away.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		mirror := mirrorcat.RemoteRef{Repository: args[0], Ref: mirrorcat.NormalizeRef(args[1])}
+		if err := mirror.Validate(); err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+
+		format, _ := cmd.Flags().GetString("output")
+		if format != "table" && format != "json" {
+			fmt.Fprintf(os.Stderr, "unrecognized output format %q\n", format)
+			os.Exit(1)
+		}
+
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		sources, closeSources, err := listableSources(ctx, cmd)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+		defer closeSources()
+
+		listed, err := collectSourcedMappings(ctx, sources, false)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+
+		mappings := make([]mirrorcat.Mapping, 0, len(listed))
+		for _, m := range listed {
+			mappings = append(mappings, m.Mapping)
+		}
+
+		// Only the mappings "mirrorcat start" would push along are of interest, so those which are excluded,
+		// overridden, or rejected are left out.
+		var merged mirrorcat.MergeFinder
+		for _, source := range sources {
+			if finder, ok := source.MirrorLister.(mirrorcat.MirrorFinder); ok {
+				merged = append(merged, prioritized(source.name, finder))
+			}
+		}
+
+		guard := mirrorcat.NewGuardedFinder(merged)
+		if _, err = guard.Analyze(ctx); err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+
+		if mappings, err = pushedMappings(ctx, guard, mappings); err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+
+		onto := make(map[mirrorcat.Mapping]struct{})
+		for _, m := range mirrorcat.NewReverseIndex(mappings).MappingsOnto(mirror, reverseLookupDepth(cmd)) {
+			onto[canonicalMapping(m)] = struct{}{}
+		}
+
+		found := []sourcedMapping{}
+		for _, m := range listed {
+			if _, ok := onto[canonicalMapping(m.Mapping)]; ok {
+				found = append(found, m)
+			}
+		}
+
+		printSourcedMappings(format, found)
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(originalsCmd)
+
+	addSourceFlags(originalsCmd)
+	originalsCmd.Flags().Bool("transitive", false, "Also print the mappings which push onto each original, and so on. Defaults to whether \"mirrorcat start\" would push transitively.")
+	originalsCmd.Flags().Int("transitive-depth", 0, "The largest number of hops away from the mirror to follow. Defaults to the same depth \"mirrorcat start\" would use.")
+	originalsCmd.Flags().Duration("timeout", time.Minute, "The longest amount of time to spend reading from all sources.")
+	originalsCmd.Flags().StringP("output", "o", "table", "The format of the list that is written. Either \"table\" or \"json\".")
+}
+
+// reverseLookupDepth finds how many hops away from a mirror "mirrorcat originals" should follow, which matches the
+// pushes "mirrorcat start" would make unless the "--transitive" or "--transitive-depth" flags say otherwise.
+func reverseLookupDepth(cmd *cobra.Command) int {
+	transitive, _ := cmd.Flags().GetBool("transitive")
+	if !transitive && !viper.GetBool("transitive") {
+		return 1
+	}
+
+	if depth, _ := cmd.Flags().GetInt("transitive-depth"); depth > 0 {
+		return depth
+	}
+	return viper.GetInt("transitive-depth")
+}
+
+// canonicalMapping strips away differences in spelling from both sides of a mapping, see `RemoteRef.Canonical`.
+func canonicalMapping(m mirrorcat.Mapping) mirrorcat.Mapping {
+	return mirrorcat.Mapping{
+		Original: m.Original.Canonical(),
+		Mirror:   m.Mirror.Canonical(),
+	}
+}
+
+// pushedMappings keeps only the mappings in `listed` that `guard` would push along. Those which have been rejected,
+// or are excluded or overridden by a source with a higher priority, are left out.
+func pushedMappings(ctx context.Context, guard *mirrorcat.GuardedFinder, listed []mirrorcat.Mapping) ([]mirrorcat.Mapping, error) {
+	found := make(map[mirrorcat.RemoteRef]map[mirrorcat.RemoteRef]struct{})
+	pushed := make([]mirrorcat.Mapping, 0, len(listed))
+
+	for _, m := range listed {
+		original := m.Original.Canonical()
+		mirrors, ok := found[original]
+		if !ok {
+			mirrors = make(map[mirrorcat.RemoteRef]struct{})
+
+			results, errs := make(chan mirrorcat.RemoteRef), make(chan error, 1)
+			go func() {
+				errs <- guard.FindMirrors(ctx, m.Original, results)
+			}()
+			for mirror := range results {
+				mirrors[mirror.Canonical()] = struct{}{}
+			}
+			if err := <-errs; err != nil {
+				return nil, err
+			}
+			found[original] = mirrors
+		}
+
+		if _, ok := mirrors[m.Mirror.Canonical()]; ok {
+			pushed = append(pushed, m)
+		}
+	}
+	return pushed, nil
+}
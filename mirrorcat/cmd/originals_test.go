@@ -0,0 +1,66 @@
+package cmd_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Azure/mirrorcat"
+)
+
+func TestOriginals_leavesOutUnpushedMappings(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mirrorcat_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Only the first original is pushed onto the mirror. The second is excluded, and the third would push in circles.
+	cfgPath := filepath.Join(dir, "mirrorcat.yml")
+	err = ioutil.WriteFile(cfgPath, []byte(`mirrors:
+  https://github.com/azure/mirrorcat.git:
+    master:
+      https://github.com/marstr/mirrorcat.git:
+        - master
+  https://github.com/haydenmc/mirrorcat.git:
+    master:
+      https://github.com/marstr/mirrorcat.git:
+        - master
+  https://github.com/azure/circular.git:
+    master:
+      https://github.com/marstr/mirrorcat.git:
+        - master
+  https://github.com/marstr/mirrorcat.git:
+    master:
+      https://github.com/azure/circular.git:
+        - master
+exclusions:
+  https://github.com/haydenmc/mirrorcat.git:
+    master:
+      https://github.com/marstr/mirrorcat.git:
+        - master
+`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, stderr, err := runMirrorCat("--config", cfgPath, "originals", "https://github.com/marstr/mirrorcat.git", "master", "--output", "json")
+	if err != nil {
+		t.Fatalf("%v: %s", err, stderr)
+	}
+
+	var found []mirrorcat.Mapping
+	if err = json.Unmarshal([]byte(stdout), &found); err != nil {
+		t.Fatalf("%v: %s", err, stdout)
+	}
+
+	want := mirrorcat.Mapping{
+		Original: mirrorcat.RemoteRef{Repository: "https://github.com/azure/mirrorcat.git", Ref: "master"},
+		Mirror:   mirrorcat.RemoteRef{Repository: "https://github.com/marstr/mirrorcat.git", Ref: "master"},
+	}
+	if len(found) != 1 || found[0] != want {
+		t.Errorf("got: %v want: %v", found, []mirrorcat.Mapping{want})
+	}
+}
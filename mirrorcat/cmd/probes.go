@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/Azure/mirrorcat"
+	"github.com/spf13/viper"
+)
+
+// DefaultMinFreeMegabytes is the space that must be available in the directory that originals are cloned into for
+// MirrorCat to report that it is ready, if not specified by the invoker of MirrorCat.
+const DefaultMinFreeMegabytes = 100
+
+// ReadinessTimeout is the longest that /readyz waits on any of MirrorCat's dependencies.
+const ReadinessTimeout = 5 * time.Second
+
+// readinessChecks holds a check for each finder backend that is in use. They are all known before any request is
+// served, so it is never modified while it is being read.
+var readinessChecks []mirrorcat.ReadinessCheck
+
+// healthReport describes the state of MirrorCat, and what was last learned about Redis if it is in use.
+type healthReport struct {
+	Status mirrorcat.ReadinessStatus `json:"status"`
+	Redis  *mirrorcat.RedisHealth    `json:"redis,omitempty"`
+}
+
+// readinessReport is a mirrorcat.ReadinessReport, alongside what was last learned about Redis if it is in use.
+type readinessReport struct {
+	mirrorcat.ReadinessReport
+	Redis *mirrorcat.RedisHealth `json:"redis,omitempty"`
+}
+
+// redisHealth fetches what the RedisMonitor last learned, if Redis is in use.
+func redisHealth() *mirrorcat.RedisHealth {
+	if redisMonitor == nil {
+		return nil
+	}
+	health := redisMonitor.Health()
+	return &health
+}
+
+// handleHealthz reports that MirrorCat is running, without consulting any of its dependencies. It always responds with
+// 200 OK, but its status is "degraded" while Redis is unreachable, and "draining" once MirrorCat has begun to shut down.
+func handleHealthz(resp http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		resp.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	report := healthReport{Status: mirrorcat.ReadinessOK, Redis: redisHealth()}
+	if pushes.Draining() {
+		report.Status = mirrorcat.ReadinessDraining
+	} else if report.Redis != nil && !report.Redis.Healthy {
+		report.Status = mirrorcat.ReadinessDegraded
+	}
+
+	resp.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(resp).Encode(report)
+}
+
+// handleReadyz reports whether MirrorCat is able to push to mirrors. It checks the config file, each finder backend,
+// git, and the space available to clone into. If any of them fail, or MirrorCat has begun to shut down, it responds
+// with 503 Service Unavailable. A failing backend that MirrorCat can carry on without, like Redis, only degrades it.
+func handleReadyz(resp http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		resp.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), ReadinessTimeout)
+	defer cancel()
+
+	checks := []mirrorcat.ReadinessCheck{
+		{Name: "config", Check: checkConfig},
+		{Name: "git", Check: mirrorcat.GitVersion},
+		{Name: "workdir", Check: checkWorkDir},
+	}
+	report := readinessReport{
+		ReadinessReport: mirrorcat.CheckReadiness(ctx, append(checks, readinessChecks...)),
+		Redis:           redisHealth(),
+	}
+	if pushes.Draining() {
+		report.Status = mirrorcat.ReadinessDraining
+		report.Ready = false
+	}
+
+	resp.Header().Set("Content-Type", "application/json")
+	if !report.Ready {
+		resp.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(resp).Encode(report)
+}
+
+// checkConfig ensures that the mirrors in the config file, if one is in use, could be read.
+func checkConfig(context.Context) (string, error) {
+	path := viper.ConfigFileUsed()
+	if path == "" {
+		return "no config file in use", nil
+	}
+
+	if viper.InConfig("mirrors") {
+		if _, ok := viper.Get("mirrors").(map[string]interface{}); !ok {
+			return "", fmt.Errorf("`mirrors` in %s was in an unexpected format", path)
+		}
+		if _, skipped := parseMirrors(viper.Get("mirrors")); len(skipped) > 0 {
+			return "", fmt.Errorf("%d mirrors in %s could not be read, the first because: %v", len(skipped), path, skipped[0])
+		}
+	}
+	return "loaded " + path, nil
+}
+
+// checkWorkDir ensures that there is at least "min-free-megabytes" of space available to clone originals into.
+func checkWorkDir(context.Context) (string, error) {
+	return mirrorcat.CheckFreeSpace(os.TempDir(), uint64(viper.GetInt("min-free-megabytes"))*1024*1024)
+}
+
+// checkReachable creates a ReadinessCheck for a finder backend from a function which contacts it.
+func checkReachable(name string, ping func(context.Context) error) mirrorcat.ReadinessCheck {
+	return mirrorcat.ReadinessCheck{
+		Name: name,
+		Check: func(ctx context.Context) (string, error) {
+			if err := ping(ctx); err != nil {
+				return "", err
+			}
+			return "reachable", nil
+		},
+	}
+}
+
+// checkRedis reports what the RedisMonitor last learned, rather than contacting Redis itself, so that it agrees with the
+// circuit breaker that decides whether Redis is asked for mirrors.
+func checkRedis(context.Context) (string, error) {
+	health := redisMonitor.Health()
+	detail := "circuit " + health.Circuit.String()
+	if health.Healthy {
+		return detail, nil
+	}
+	if health.LastError == "" {
+		return detail, errors.New("has not been checked yet")
+	}
+	return detail, errors.New(health.LastError)
+}
+
+// checkDirectory creates a ReadinessCheck which ensures that a directory of mapping files can be read.
+func checkDirectory(name, dir string) mirrorcat.ReadinessCheck {
+	return checkReachable(name, func(context.Context) error {
+		info, err := os.Stat(dir)
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return errors.New(dir + " is not a directory")
+		}
+		return nil
+	})
+}
@@ -0,0 +1,44 @@
+package cmd_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/Azure/mirrorcat"
+)
+
+func TestReadyz_redisUnreachable(t *testing.T) {
+	port := freePort(t)
+	stop := startMirrorCat(t, port, "start", "--port", strconv.Itoa(port), "--min-free-megabytes", "0", "--redis-connection", fmt.Sprintf("redis://localhost:%d", freePort(t)))
+	defer stop()
+
+	resp, err := http.Get(fmt.Sprintf("http://localhost:%d/readyz", port))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var report mirrorcat.ReadinessReport
+	if err = json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		t.Fatal(err)
+	}
+
+	// Lookups skip Redis while it can't be reached, so MirrorCat can still push to the other mirrors it knows about.
+	if resp.StatusCode != http.StatusOK || !report.Ready || report.Status != mirrorcat.ReadinessDegraded {
+		t.Fatalf("got: %d %v want: 200, and a degraded report", resp.StatusCode, report)
+	}
+
+	for _, check := range report.Checks {
+		if check.Name == "redis" {
+			if check.Ready || !check.Degraded {
+				t.Logf("got: %v want: a degraded check", check)
+				t.Fail()
+			}
+			return
+		}
+	}
+	t.Errorf("got: %v want: a redis check", report.Checks)
+}
@@ -0,0 +1,252 @@
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"strings"
+
+	"github.com/Azure/mirrorcat"
+	"github.com/go-redis/redis"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// redisCmd represents the redis command
+var redisCmd = &cobra.Command{
+	Use:   "redis",
+	Short: "Commands for administering the mappings that MirrorCat reads from Redis.",
+}
+
+func init() {
+	RootCmd.AddCommand(redisCmd)
+
+	redisCmd.PersistentFlags().StringP("redis-connection", "r", "", "The URL of a single Redis server to administer. Defaults to the same Redis deployment \"mirrorcat start\" would use.")
+}
+
+// connectRedis creates a client for the Redis deployment that "mirrorcat start" would use. The "--redis-connection" flag
+// may be provided to use a different single Redis server instead.
+func connectRedis(cmd *cobra.Command) (redis.UniversalClient, error) {
+	connection, _ := cmd.Flags().GetString("redis-connection")
+	if connection == "" {
+		connection = viper.GetString("redis-connection")
+	} else {
+		viper.Set("redis-sentinel-master", "")
+		viper.Set("redis-cluster-addrs", []string{})
+	}
+
+	client, _, err := newRedisClient(connection)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = client.Ping().Err(); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+// newRedisClient creates a client for the Redis deployment described by the "redis-*" settings. A Sentinel-managed
+// failover group is used if "redis-sentinel-master" is set, then a Redis Cluster if "redis-cluster-addrs" is set.
+// Otherwise, `connection` is parsed as the URL of a single Redis server.
+//
+// The returned description is suitable for logging, and never includes a password.
+func newRedisClient(connection string) (client redis.UniversalClient, description string, err error) {
+	tlsConfig, err := redisTLSConfig()
+	if err != nil {
+		return
+	}
+
+	password := viper.GetString("redis-password")
+
+	if master := viper.GetString("redis-sentinel-master"); master != "" {
+		sentinels := listSetting("redis-sentinel-addrs")
+		if len(sentinels) == 0 {
+			err = errors.New("\"redis-sentinel-master\" requires at least one of \"redis-sentinel-addrs\"")
+			return
+		}
+
+		client = redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:    master,
+			SentinelAddrs: sentinels,
+			Password:      password,
+			DB:            viper.GetInt("redis-db"),
+			TLSConfig:     tlsConfig,
+		})
+		description = fmt.Sprintf("master %q from sentinels %s", master, strings.Join(sentinels, ", "))
+		return
+	}
+
+	if nodes := listSetting("redis-cluster-addrs"); len(nodes) > 0 {
+		client = redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:     nodes,
+			Password:  password,
+			TLSConfig: tlsConfig,
+		})
+		description = "cluster " + strings.Join(nodes, ", ")
+		return
+	}
+
+	if connection == "" {
+		err = errors.New("no Redis connection was provided, see --redis-connection")
+		return
+	}
+
+	options, err := redis.ParseURL(connection)
+	if err != nil {
+		return
+	}
+
+	if password != "" {
+		options.Password = password
+	}
+	if db := viper.GetInt("redis-db"); db != 0 {
+		options.DB = db
+	}
+	if tlsConfig != nil {
+		tlsConfig.ServerName, _, _ = net.SplitHostPort(options.Addr)
+		options.TLSConfig = tlsConfig
+	}
+
+	client = redis.NewClient(options)
+	description = options.Addr
+	return
+}
+
+// redisTLSConfig reads the "redis-tls", "redis-tls-skip-verify", and "redis-tls-ca-file" settings. If TLS has not been
+// enabled, a nil config is returned.
+func redisTLSConfig() (*tls.Config, error) {
+	if !viper.GetBool("redis-tls") {
+		return nil, nil
+	}
+
+	config := &tls.Config{
+		InsecureSkipVerify: viper.GetBool("redis-tls-skip-verify"),
+	}
+
+	if caFile := viper.GetString("redis-tls-ca-file"); caFile != "" {
+		pem, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates could be read from %q", caFile)
+		}
+	}
+	return config, nil
+}
+
+// listSetting reads a setting which holds a list, such as one of addresses. Environment variables can only hold a
+// single string, so each entry is also split on commas.
+func listSetting(key string) (items []string) {
+	for _, entry := range viper.GetStringSlice(key) {
+		for _, item := range strings.Split(entry, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+	}
+	return
+}
+
+// redisEncoding reads the "--encoding" flag of a command, which applies to every mapping. Without it, each
+// mapping is stored using the encoding that "redis-json-prefixes" chooses for its original.
+func redisEncoding(cmd *cobra.Command) (func(original mirrorcat.RemoteRef) mirrorcat.RedisRefEncoding, error) {
+	if !cmd.Flags().Changed("encoding") {
+		prefixes := listSetting("redis-json-prefixes")
+		return func(original mirrorcat.RemoteRef) mirrorcat.RedisRefEncoding {
+			return mirrorcat.RedisEncodingFor(original, prefixes)
+		}, nil
+	}
+
+	name, _ := cmd.Flags().GetString("encoding")
+	enc, err := mirrorcat.ParseRedisRefEncoding(name)
+	if err != nil {
+		return nil, err
+	}
+	return func(mirrorcat.RemoteRef) mirrorcat.RedisRefEncoding { return enc }, nil
+}
+
+// mappingFromArgs reads a mapping from the arguments "{original repository} {original ref} {mirror repository} {mirror ref}",
+// ensuring that it is well-formed.
+func mappingFromArgs(args []string) (m mirrorcat.Mapping, err error) {
+	m = parseMappingArgs(args)
+	err = validateMapping(m)
+	return
+}
+
+// encodedMappingFromArgs behaves like mappingFromArgs, but only rejects refs that `enc` is unable to store in Redis.
+func encodedMappingFromArgs(args []string, enc mirrorcat.RedisRefEncoding) (m mirrorcat.Mapping, err error) {
+	m = parseMappingArgs(args)
+	err = validateEncodedMapping(m, enc)
+	return
+}
+
+// parseMappingArgs reads a mapping from the arguments "{original repository} {original ref} {mirror repository} {mirror ref}".
+func parseMappingArgs(args []string) mirrorcat.Mapping {
+	return mirrorcat.Mapping{
+		Original: mirrorcat.RemoteRef{Repository: args[0], Ref: mirrorcat.NormalizeRef(args[1])},
+		Mirror:   mirrorcat.RemoteRef{Repository: args[2], Ref: mirrorcat.NormalizeRef(args[3])},
+	}
+}
+
+// validateMapping ensures that a mapping is well-formed, and would not push a branch onto itself.
+func validateMapping(m mirrorcat.Mapping) error {
+	return checkMapping(m, mirrorcat.RemoteRef.Validate)
+}
+
+// validateEncodedMapping behaves like validateMapping, but only rejects refs that `enc` is unable to store in Redis.
+func validateEncodedMapping(m mirrorcat.Mapping, enc mirrorcat.RedisRefEncoding) error {
+	return checkMapping(m, func(rr mirrorcat.RemoteRef) error {
+		return rr.ValidateFor(enc)
+	})
+}
+
+// checkMapping ensures that both sides of a mapping pass `validate`, and that it would not push a branch onto itself.
+func checkMapping(m mirrorcat.Mapping, validate func(mirrorcat.RemoteRef) error) error {
+	if err := validate(m.Original); err != nil {
+		return fmt.Errorf("original is invalid: %v", err)
+	}
+	if err := validate(m.Mirror); err != nil {
+		return fmt.Errorf("mirror is invalid: %v", err)
+	}
+	if m.IsSelfMirror() {
+		return fmt.Errorf("%s (%s) would be mirrored onto itself", m.Original.Repository, m.Original.Ref)
+	}
+	return nil
+}
+
+// loadRedisMappings reads every mirror and exclusion stored in Redis, in either schema.
+func loadRedisMappings(ctx context.Context, client redis.UniversalClient) (mirrors, exclusions []mirrorcat.Mapping, err error) {
+	index := mirrorcat.NewRedisIndex(client)
+	if err = index.Load(ctx); err != nil {
+		return
+	}
+
+	if mirrors, err = mirrorcat.CollectMappings(ctx, index); err != nil {
+		return
+	}
+	exclusions, err = mirrorcat.CollectMappings(ctx, exclusionLister{index})
+	return
+}
+
+// exclusionSource is implemented by sources which are able to enumerate their exclusions.
+type exclusionSource interface {
+	ListExclusions(context.Context, chan<- mirrorcat.Mapping) error
+}
+
+// exclusionLister enumerates the exclusions of a source as though they were mirrors.
+type exclusionLister struct {
+	exclusionSource
+}
+
+func (el exclusionLister) ListMirrors(ctx context.Context, results chan<- mirrorcat.Mapping) error {
+	return el.ListExclusions(ctx, results)
+}
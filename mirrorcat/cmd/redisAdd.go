@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Azure/mirrorcat"
+	"github.com/spf13/cobra"
+)
+
+// redisAddCmd represents the redis add command
+var redisAddCmd = &cobra.Command{
+	Use:   "add {original repository} {original ref} {mirror repository} {mirror ref}",
+	Short: "Stores a mapping in Redis.",
+	Long: `Stores a mapping in Redis, so that each commit pushed to the original ref is also
+pushed to the mirror ref. Both repositories and refs are validated before anything is written.
+Refs containing a colon may only be stored with --encoding json, or when the original is matched
+by the redis-json-prefixes setting.
+
+Options such as --depth and --force are stored alongside the mapping. If the mapping
+already exists, its options are replaced.`,
+	Args: cobra.ExactArgs(4),
+	Run: func(cmd *cobra.Command, args []string) {
+		encodingOf, err := redisEncoding(cmd)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+
+		enc := encodingOf(parseMappingArgs(args).Original)
+		m, err := encodedMappingFromArgs(args, enc)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+
+		client, err := connectRedis(cmd)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+		defer client.Close()
+
+		exclusion, _ := cmd.Flags().GetBool("exclusion")
+		legacy, _ := cmd.Flags().GetBool("legacy")
+
+		switch {
+		case exclusion:
+			err = client.SAdd(mirrorcat.RedisExclusionPrefix+mirrorcat.EncodeRedisRemoteRef(m.Original, enc), mirrorcat.EncodeRedisRemoteRef(m.Mirror, enc)).Err()
+		case legacy:
+			err = client.SAdd(mirrorcat.EncodeRedisRemoteRef(m.Original, enc), mirrorcat.EncodeRedisRemoteRef(m.Mirror, enc)).Err()
+		default:
+			var options mirrorcat.MirrorOptions
+			options.Depth, _ = cmd.Flags().GetInt("depth")
+			options.Force, _ = cmd.Flags().GetBool("force")
+			options.Credentials, _ = cmd.Flags().GetString("credentials")
+			options.Owner, _ = cmd.Flags().GetString("owner")
+			if ttl, _ := cmd.Flags().GetDuration("ttl"); ttl > 0 {
+				options.Expires = time.Now().Add(ttl)
+			}
+			err = mirrorcat.SaveRedisMapping(client, m, options, enc)
+		}
+
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+		fmt.Printf("Added %s -> %s\n", mirrorcat.RedisRemoteRef(m.Original), mirrorcat.RedisRemoteRef(m.Mirror))
+	},
+}
+
+func init() {
+	redisCmd.AddCommand(redisAddCmd)
+
+	redisAddCmd.Flags().Int("depth", 0, "The number of commits that the mirror needs. The original is cloned at least this deeply.")
+	redisAddCmd.Flags().Bool("force", false, "Overwrite the mirror's branch, even if it has commits that the original does not.")
+	redisAddCmd.Flags().String("credentials", "", "The name of an environment variable holding the access token to push to the mirror with.")
+	redisAddCmd.Flags().String("owner", "", "Who is responsible for the mirror.")
+	redisAddCmd.Flags().Duration("ttl", 0, "How long until the mapping is forgotten. By default, it is kept forever.")
+	redisAddCmd.Flags().Bool("legacy", false, "Store the mapping as a bare Set member, which older versions of MirrorCat understand. Options are ignored.")
+	redisAddCmd.Flags().String("encoding", mirrorcat.RedisColonEncoding.String(), "How repositories and refs are written. Either \"colon\", which all versions of MirrorCat understand, or \"json\", which can represent any ref. Defaults to \"json\" for originals matched by the redis-json-prefixes setting, and \"colon\" for all others.")
+	redisAddCmd.Flags().Bool("exclusion", false, "Store an exclusion, which prevents the mirror from being pushed to, rather than a mapping. Options are ignored.")
+}
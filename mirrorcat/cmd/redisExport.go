@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/Azure/mirrorcat"
+	"github.com/spf13/cobra"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// redisExportCmd represents the redis export command
+var redisExportCmd = &cobra.Command{
+	Use:   "export [file]",
+	Short: "Writes every mapping stored in Redis as YAML.",
+	Long: `Writes every mapping and exclusion stored in Redis as YAML, in the same shape as the
+"mirrors" and "exclusions" properties of a config file. If no file is provided, the
+YAML is written to stdout.
+
+Options stored alongside mappings are not exported.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := connectRedis(cmd)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+		defer client.Close()
+
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		mirrors, exclusions, err := loadRedisMappings(ctx, client)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+
+		marshaled, err := yaml.Marshal(mappingDocument{
+			Mirrors:    newMappingTree(mirrors),
+			Exclusions: newMappingTree(exclusions),
+		})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+
+		if len(args) == 0 {
+			os.Stdout.Write(marshaled)
+			return
+		}
+
+		if err = ioutil.WriteFile(args[0], marshaled, 0644); err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+		fmt.Printf("Exported %d mappings and %d exclusions to %s\n", len(mirrors), len(exclusions), args[0])
+	},
+}
+
+func init() {
+	redisCmd.AddCommand(redisExportCmd)
+
+	redisExportCmd.Flags().Duration("timeout", time.Minute, "The longest amount of time to spend reading from Redis.")
+}
+
+// mappingDocument is the shape of the files read and written by "mirrorcat redis import" and "mirrorcat redis export".
+// It matches the `mirrors` and `exclusions` properties of a config file.
+type mappingDocument struct {
+	Mirrors    mappingTree `yaml:"mirrors,omitempty"`
+	Exclusions mappingTree `yaml:"exclusions,omitempty"`
+}
+
+// mappingTree arranges mappings by original repository, then original ref, then mirror repository.
+type mappingTree map[string]map[string]map[string][]string
+
+func newMappingTree(mappings []mirrorcat.Mapping) mappingTree {
+	if len(mappings) == 0 {
+		return nil
+	}
+
+	tree := make(mappingTree)
+	for _, m := range mappings {
+		refs, ok := tree[m.Original.Repository]
+		if !ok {
+			refs = make(map[string]map[string][]string)
+			tree[m.Original.Repository] = refs
+		}
+
+		remotes, ok := refs[m.Original.Ref]
+		if !ok {
+			remotes = make(map[string][]string)
+			refs[m.Original.Ref] = remotes
+		}
+
+		remotes[m.Mirror.Repository] = append(remotes[m.Mirror.Repository], m.Mirror.Ref)
+	}
+	return tree
+}
@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/Azure/mirrorcat"
+	"github.com/spf13/cobra"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// redisImportCmd represents the redis import command
+var redisImportCmd = &cobra.Command{
+	Use:   "import {file}",
+	Short: "Stores every mapping in a YAML or JSON file in Redis.",
+	Long: `Reads the "mirrors" and "exclusions" properties of a file in the same shape as a
+config file, such as one written by "mirrorcat redis export", and stores each of them
+in Redis. Mappings that are already in Redis keep the options stored alongside them.
+
+Every mapping is validated before any are written. If a problem is found, nothing is
+written and the command exits with a non-zero status.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		encodingOf, err := redisEncoding(cmd)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+
+		mirrors, exclusions, problems := readMappingDocument(args[0], encodingOf)
+		if len(problems) > 0 {
+			for _, problem := range problems {
+				fmt.Fprintln(os.Stderr, problem.Error())
+			}
+			os.Exit(1)
+		}
+
+		if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+			fmt.Printf("Found %d valid mappings and %d valid exclusions in %s\n", len(mirrors), len(exclusions), args[0])
+			return
+		}
+
+		client, err := connectRedis(cmd)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+		defer client.Close()
+
+		legacy, _ := cmd.Flags().GetBool("legacy")
+
+		for _, m := range mirrors {
+			enc := encodingOf(m.Original)
+			if legacy {
+				err = client.SAdd(mirrorcat.EncodeRedisRemoteRef(m.Original, enc), mirrorcat.EncodeRedisRemoteRef(m.Mirror, enc)).Err()
+			} else if _, found, readErr := mirrorcat.ReadRedisOptions(client, m); readErr != nil {
+				err = readErr
+			} else if !found {
+				err = mirrorcat.SaveRedisMapping(client, m, mirrorcat.MirrorOptions{}, enc)
+			}
+
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err.Error())
+				os.Exit(1)
+			}
+		}
+
+		for _, m := range exclusions {
+			enc := encodingOf(m.Original)
+			err = client.SAdd(mirrorcat.RedisExclusionPrefix+mirrorcat.EncodeRedisRemoteRef(m.Original, enc), mirrorcat.EncodeRedisRemoteRef(m.Mirror, enc)).Err()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err.Error())
+				os.Exit(1)
+			}
+		}
+
+		fmt.Printf("Imported %d mappings and %d exclusions from %s\n", len(mirrors), len(exclusions), args[0])
+	},
+}
+
+func init() {
+	redisCmd.AddCommand(redisImportCmd)
+
+	redisImportCmd.Flags().Bool("dry-run", false, "Only validate the file, without contacting Redis.")
+	redisImportCmd.Flags().String("encoding", mirrorcat.RedisColonEncoding.String(), "How repositories and refs are written. Either \"colon\", which all versions of MirrorCat understand, or \"json\", which can represent any ref. Defaults to \"json\" for originals matched by the redis-json-prefixes setting, and \"colon\" for all others.")
+	redisImportCmd.Flags().Bool("legacy", false, "Store mappings as bare Set members, which older versions of MirrorCat understand.")
+}
+
+// readMappingDocument reads and validates the mappings in a file shaped like a `mappingDocument`. Refs are only
+// rejected if the encoding `encodingOf` chooses for their original is unable to store them.
+func readMappingDocument(path string, encodingOf func(original mirrorcat.RemoteRef) mirrorcat.RedisRefEncoding) (mirrors, exclusions []mirrorcat.Mapping, problems []error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		problems = append(problems, err)
+		return
+	}
+
+	var raw map[string]interface{}
+	if err = yaml.Unmarshal(contents, &raw); err != nil {
+		problems = append(problems, err)
+		return
+	}
+
+	if rawMirrors, ok := raw["mirrors"]; ok {
+		var skipped []error
+		mirrors, skipped = parseMirrors(stringKeyed(rawMirrors))
+		problems = append(problems, skipped...)
+	}
+
+	if rawExclusions, ok := raw["exclusions"]; ok {
+		var skipped []error
+		exclusions, skipped = parseMirrors(stringKeyed(rawExclusions))
+		problems = append(problems, skipped...)
+	}
+
+	for _, m := range append(append([]mirrorcat.Mapping(nil), mirrors...), exclusions...) {
+		if err := validateEncodedMapping(m, encodingOf(m.Original)); err != nil {
+			problems = append(problems, fmt.Errorf("%s -> %s: %v", mirrorcat.RedisRemoteRef(m.Original), mirrorcat.RedisRemoteRef(m.Mirror), err))
+		}
+	}
+	return
+}
+
+// stringKeyed converts the maps produced by unmarshaling YAML, which may have keys of any type,
+// into the `map[string]interface{}` that `parseMirrors` expects.
+func stringKeyed(raw interface{}) interface{} {
+	switch typed := raw.(type) {
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(typed))
+		for key, value := range typed {
+			converted[fmt.Sprint(key)] = stringKeyed(value)
+		}
+		return converted
+	case []interface{}:
+		converted := make([]interface{}, len(typed))
+		for i, value := range typed {
+			converted[i] = stringKeyed(value)
+		}
+		return converted
+	default:
+		return raw
+	}
+}
@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Azure/mirrorcat"
+	"github.com/spf13/cobra"
+)
+
+// redisListCmd represents the redis list command
+var redisListCmd = &cobra.Command{
+	Use:   "list [original repository [original ref]]",
+	Short: "Prints the mappings stored in Redis.",
+	Long: `Prints each mapping stored in Redis, along with any options stored alongside it.
+Providing an original repository, and optionally a ref, only prints the mappings of that original.`,
+	Args: cobra.MaximumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		format, _ := cmd.Flags().GetString("output")
+		if format != "text" && format != "json" {
+			fmt.Fprintf(os.Stderr, "unrecognized output format %q\n", format)
+			os.Exit(1)
+		}
+
+		client, err := connectRedis(cmd)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+		defer client.Close()
+
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		mirrors, exclusions, err := loadRedisMappings(ctx, client)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+
+		found := mirrors
+		if exclusion, _ := cmd.Flags().GetBool("exclusions"); exclusion {
+			found = exclusions
+		}
+
+		listed := make([]listedMapping, 0, len(found))
+		for _, m := range found {
+			if len(args) > 0 && m.Original.Repository != args[0] {
+				continue
+			}
+			if len(args) > 1 && m.Original.Ref != mirrorcat.NormalizeRef(args[1]) {
+				continue
+			}
+
+			entry := listedMapping{Mapping: m}
+			options, ok, err := mirrorcat.ReadRedisOptions(client, m)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err.Error())
+				os.Exit(1)
+			}
+			if ok && options != (mirrorcat.MirrorOptions{}) {
+				entry.Options = &options
+			}
+			listed = append(listed, entry)
+		}
+
+		if format == "json" {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			encoder.Encode(listed)
+			return
+		}
+
+		for _, entry := range listed {
+			fmt.Println(entry.String())
+		}
+	},
+}
+
+func init() {
+	redisCmd.AddCommand(redisListCmd)
+
+	redisListCmd.Flags().Bool("exclusions", false, "Print exclusions, rather than mappings.")
+	redisListCmd.Flags().Duration("timeout", time.Minute, "The longest amount of time to spend reading from Redis.")
+	redisListCmd.Flags().StringP("output", "o", "text", "The format of the list that is written. Either \"text\" or \"json\".")
+}
+
+// listedMapping is a single entry printed by "mirrorcat redis list".
+type listedMapping struct {
+	mirrorcat.Mapping
+	Options *mirrorcat.MirrorOptions `json:"options,omitempty"`
+}
+
+func (lm listedMapping) String() string {
+	line := fmt.Sprintf("%s -> %s", mirrorcat.RedisRemoteRef(lm.Original), mirrorcat.RedisRemoteRef(lm.Mirror))
+	if lm.Options == nil {
+		return line
+	}
+
+	var details []string
+	if lm.Options.Depth > 0 {
+		details = append(details, fmt.Sprintf("depth=%d", lm.Options.Depth))
+	}
+	if lm.Options.Force {
+		details = append(details, "force")
+	}
+	if lm.Options.Credentials != "" {
+		details = append(details, "credentials="+lm.Options.Credentials)
+	}
+	if lm.Options.Owner != "" {
+		details = append(details, "owner="+lm.Options.Owner)
+	}
+	if !lm.Options.Expires.IsZero() {
+		details = append(details, "expires="+lm.Options.Expires.Format(time.RFC3339))
+	}
+	return fmt.Sprintf("%s [%s]", line, strings.Join(details, " "))
+}
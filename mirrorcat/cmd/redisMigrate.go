@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Azure/mirrorcat"
+	"github.com/spf13/cobra"
+)
+
+// redisMigrateCmd represents the redis migrate command
+var redisMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Converts mappings stored in Redis to the newest schema.",
+	Long: `Copies every mapping stored as a bare Set of "ref:repository" strings into the
+newest Redis schema, where each mapping is a Hash that may hold options such as a
+clone depth, force-push policy, owner, or expiry.
+
+Once each of its mirrors has been copied, the original Set is deleted unless
+--keep-legacy is provided. Exclusions are not changed.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := connectRedis(cmd)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+		defer client.Close()
+
+		keepLegacy, _ := cmd.Flags().GetBool("keep-legacy")
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		migrated, err := mirrorcat.MigrateRedisMappings(ctx, client, keepLegacy)
+		for _, m := range migrated {
+			fmt.Printf("%s -> %s\n", mirrorcat.RedisRemoteRef(m.Original), mirrorcat.RedisRemoteRef(m.Mirror))
+		}
+		fmt.Printf("Migrated %d mappings to schema version %d.\n", len(migrated), mirrorcat.RedisSchemaVersion)
+
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	redisCmd.AddCommand(redisMigrateCmd)
+
+	redisMigrateCmd.Flags().Bool("keep-legacy", false, "Leave each Set in the original format in place after its mirrors have been copied.")
+	redisMigrateCmd.Flags().Duration("timeout", 5*time.Minute, "The longest amount of time to spend migrating.")
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Azure/mirrorcat"
+	"github.com/go-redis/redis"
+	"github.com/spf13/cobra"
+)
+
+// redisRemoveCmd represents the redis remove command
+var redisRemoveCmd = &cobra.Command{
+	Use:   "remove {original repository} {original ref} {mirror repository} {mirror ref}",
+	Short: "Deletes a mapping from Redis.",
+	Long: `Deletes a mapping from Redis, regardless of which schema it was stored with.
+The command exits with a non-zero status if there was no such mapping.`,
+	Args: cobra.ExactArgs(4),
+	Run: func(cmd *cobra.Command, args []string) {
+		// The mapping may have been stored with either encoding, so refs are only rejected if neither could store them.
+		m, err := encodedMappingFromArgs(args, mirrorcat.RedisJSONEncoding)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+
+		client, err := connectRedis(cmd)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+		defer client.Close()
+
+		var removed int64
+
+		if exclusion, _ := cmd.Flags().GetBool("exclusion"); exclusion {
+			removed, err = removeRedisMembers(client, mirrorcat.RedisExclusionPrefix, m)
+		} else {
+			var found bool
+			if _, found, err = mirrorcat.ReadRedisOptions(client, m); err == nil && found {
+				removed++
+				err = mirrorcat.DeleteRedisMapping(client, m)
+			}
+
+			if err == nil {
+				var legacy int64
+				legacy, err = removeRedisMembers(client, "", m)
+				removed += legacy
+			}
+		}
+
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+
+		if removed == 0 {
+			fmt.Fprintf(os.Stderr, "No mapping from %s to %s was found\n", mirrorcat.RedisRemoteRef(m.Original), mirrorcat.RedisRemoteRef(m.Mirror))
+			os.Exit(1)
+		}
+		fmt.Printf("Removed %s -> %s\n", mirrorcat.RedisRemoteRef(m.Original), mirrorcat.RedisRemoteRef(m.Mirror))
+	},
+}
+
+func init() {
+	redisCmd.AddCommand(redisRemoveCmd)
+
+	redisRemoveCmd.Flags().Bool("exclusion", false, "Remove an exclusion, rather than a mapping.")
+}
+
+// removeRedisMembers removes `m.Mirror` from the version 1 Set of `m.Original` at `prefix`, with every combination
+// of encodings that the key and member may have been written with.
+func removeRedisMembers(client redis.UniversalClient, prefix string, m mirrorcat.Mapping) (removed int64, err error) {
+	encodings := []mirrorcat.RedisRefEncoding{mirrorcat.RedisColonEncoding, mirrorcat.RedisJSONEncoding}
+
+	for _, keyEnc := range encodings {
+		key := prefix + mirrorcat.EncodeRedisRemoteRef(m.Original, keyEnc)
+		for _, memberEnc := range encodings {
+			var count int64
+			if count, err = client.SRem(key, mirrorcat.EncodeRedisRemoteRef(m.Mirror, memberEnc)).Result(); err != nil {
+				return
+			}
+			removed += count
+		}
+	}
+	return
+}
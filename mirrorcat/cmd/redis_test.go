@@ -0,0 +1,140 @@
+package cmd_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/spf13/viper"
+
+	"github.com/Azure/mirrorcat"
+	"github.com/Azure/mirrorcat/mirrorcat/cmd"
+)
+
+// processArgsVariable holds the arguments, one per line, that TestMirrorCatProcess runs the mirrorcat command with.
+const processArgsVariable = "MIRRORCAT_TEST_PROCESS_ARGS"
+
+// TestMirrorCatProcess isn't a real test. It runs the mirrorcat command in the process started by runMirrorCat.
+func TestMirrorCatProcess(t *testing.T) {
+	raw, ok := os.LookupEnv(processArgsVariable)
+	if !ok {
+		return
+	}
+
+	cmd.RootCmd.SetArgs(strings.Split(raw, "\n"))
+	if err := cmd.RootCmd.Execute(); err != nil {
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+// runMirrorCat runs the mirrorcat command with `args` in a separate process, because commands exit when they fail.
+func runMirrorCat(args ...string) (stdout, stderr string, err error) {
+	process := exec.Command(os.Args[0], "-test.run=^TestMirrorCatProcess$")
+	process.Env = append(os.Environ(), processArgsVariable+"="+strings.Join(args, "\n"))
+
+	output, diagnostics := &bytes.Buffer{}, &bytes.Buffer{}
+	process.Stdout = output
+	process.Stderr = diagnostics
+	err = process.Run()
+	return output.String(), diagnostics.String(), err
+}
+
+func TestRedisAdd_jsonEncoding(t *testing.T) {
+	viper.BindEnv("redis-connection", "MIRRORCAT_REDIS_CONNECTION")
+	viper.SetDefault("redis-connection", "redis://localhost:6379")
+	connection := viper.GetString("redis-connection")
+
+	original := mirrorcat.RemoteRef{Repository: "https://github.com/Azure/mirrorcat.git", Ref: "release:2018"}
+	mirror := mirrorcat.RemoteRef{Repository: "https://github.com/marstr/mirrorcat.git", Ref: "release:2018"}
+	args := []string{"redis", "add", original.Repository, original.Ref, mirror.Repository, mirror.Ref, "-r", connection}
+
+	// The original encoding can't tell where a ref with a colon ends, so it is refused before Redis is contacted.
+	if _, stderr, err := runMirrorCat(append(args, "--encoding", "colon")...); err == nil || !strings.Contains(stderr, "disallowed character ':'") {
+		t.Errorf("got: %v %q want: the colon to be disallowed", err, stderr)
+	}
+
+	options, err := redis.ParseURL(connection)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := redis.NewClient(options)
+	defer client.Close()
+	if err = client.Ping().Err(); err != nil {
+		t.Log("Unable to connect to Redis instance: ", err)
+		t.SkipNow()
+	}
+
+	if _, stderr, err := runMirrorCat(append(args, "--encoding", "json")...); err != nil {
+		t.Fatalf("%v: %s", err, stderr)
+	}
+	defer runMirrorCat("redis", "remove", original.Repository, original.Ref, mirror.Repository, mirror.Ref, "-r", connection)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	index := mirrorcat.NewRedisIndex(client)
+	if err = index.Load(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	results := make(chan mirrorcat.RemoteRef)
+	go index.FindMirrors(ctx, original, results)
+
+	found := false
+	for result := range results {
+		found = found || result == mirror
+	}
+	if !found {
+		t.Errorf("%v was not stored as a mirror of %v", mirror, original)
+	}
+}
+
+func TestRedisImport_jsonPrefixes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mirrorcat_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	docPath := filepath.Join(dir, "mappings.yml")
+	err = ioutil.WriteFile(docPath, []byte(`mirrors:
+  https://github.com/Azure/mirrorcat.git:
+    release:2018:
+      https://github.com/marstr/mirrorcat.git:
+        - release:2018
+`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfgPath := filepath.Join(dir, "mirrorcat.yml")
+	if err = ioutil.WriteFile(cfgPath, []byte("redis-json-prefixes:\n- \"release:\"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	emptyPath := filepath.Join(dir, "empty.yml")
+	if err = ioutil.WriteFile(emptyPath, []byte("{}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, stderr, err := runMirrorCat("--config", emptyPath, "redis", "import", docPath, "--dry-run"); err == nil || !strings.Contains(stderr, "disallowed character ':'") {
+		t.Errorf("got: %v %q want: the colon to be disallowed", err, stderr)
+	}
+
+	if _, stderr, err := runMirrorCat("--config", cfgPath, "redis", "import", docPath, "--dry-run"); err != nil {
+		t.Errorf("%v: %s", err, stderr)
+	}
+
+	// An explicit encoding takes precedence over the prefixes.
+	if _, stderr, err := runMirrorCat("--config", cfgPath, "redis", "import", docPath, "--dry-run", "--encoding", "colon"); err == nil || !strings.Contains(stderr, "disallowed character ':'") {
+		t.Errorf("got: %v %q want: the colon to be disallowed", err, stderr)
+	}
+}
@@ -2,9 +2,9 @@ package cmd
 
 import (
 	"fmt"
-	"log"
 	"os"
 
+	"github.com/Azure/mirrorcat"
 	"github.com/mitchellh/go-homedir"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -37,12 +37,12 @@ func Execute() {
 }
 
 func init() {
-	//cobra.OnInitialize(initConfig)
+	cobra.OnInitialize(initConfig)
 
 	// Here you will define your flags and configuration settings.
 	// Cobra supports persistent flags, which, if defined here,
 	// will be global for your application.
-	//RootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.mirrorcat.yaml)")
+	RootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "The configuration file that should be used for all settings. Defaults to $MIRRORCAT_CONFIG, then $HOME/.mirrorcat.yml.")
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
@@ -71,7 +71,7 @@ func initConfig() {
 
 	// If a config file is found, read it in.
 	if err := viper.ReadInConfig(); err == nil {
-		log.Println("Using config file:", viper.ConfigFileUsed())
+		mirrorcat.Logger.WithField("config", viper.ConfigFileUsed()).Info("Using config file")
 		viper.WatchConfig()
 	}
 }
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/Azure/mirrorcat"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultShutdownGracePeriod is how long MirrorCat waits for pushes in progress to finish once it has been asked to
+// stop, if not specified by the invoker of MirrorCat. It fits within the 30 seconds that Kubernetes waits by default
+// before killing a container.
+const DefaultShutdownGracePeriod = 25 * time.Second
+
+// These are the statuses that `mirrorcat start` exits with.
+const (
+	// ExitDrained means that MirrorCat was asked to stop, and every push in progress finished.
+	ExitDrained = 0
+
+	// ExitServeFailed means that MirrorCat was unable to serve requests, for instance because its port was in use.
+	ExitServeFailed = 1
+
+	// ExitAbandoned means that MirrorCat was asked to stop, and the grace period ended before every push in
+	// progress finished. The pushes that remained were cancelled.
+	ExitAbandoned = 2
+)
+
+// pushes tracks each push in progress, whether it was triggered by a push event or by a mirror being added to Redis.
+var pushes = mirrorcat.NewInFlight()
+
+// serve answers requests until the server fails, or MirrorCat receives SIGINT or SIGTERM. It returns the status that
+// MirrorCat should exit with.
+func serve(server *http.Server, grace time.Duration) int {
+	failed := make(chan error, 1)
+	go func() {
+		failed <- server.ListenAndServe()
+	}()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	select {
+	case err := <-failed:
+		mirrorcat.Logger.WithError(err).Error("Unable to serve requests")
+		return ExitServeFailed
+	case received := <-signals:
+		mirrorcat.Logger.WithFields(logrus.Fields{
+			"signal": received.String(),
+			"grace":  grace.String(),
+		}).Info("Waiting for pushes in progress to finish")
+	}
+
+	return drain(server, grace)
+}
+
+// drain stops accepting webhooks, then waits up to `grace` for pushes in progress to finish. Pushes which have not
+// finished by then are cancelled, which stops their git processes and removes their clones.
+func drain(server *http.Server, grace time.Duration) int {
+	ctx, cancel := context.WithTimeout(context.Background(), grace)
+	defer cancel()
+
+	// Closing the listener doesn't stop a webhook from arriving on a connection which is already open, so pushes are
+	// refused as well.
+	drained := make(chan error, 1)
+	go func() {
+		drained <- pushes.Drain(ctx)
+	}()
+
+	if err := server.Shutdown(ctx); err != nil {
+		mirrorcat.Logger.WithError(err).Warn("Stopped waiting for requests to finish")
+	}
+
+	if err := <-drained; err != nil {
+		mirrorcat.Logger.WithError(err).Warn("Cancelled the pushes which were still in progress")
+		return ExitAbandoned
+	}
+
+	mirrorcat.Logger.Info("Every push in progress finished, exiting")
+	return ExitDrained
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Azure/mirrorcat"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	// These drivers allow MirrorCat to store mappings in PostgreSQL, or in a SQLite file on the local disk.
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// sqlCmd represents the sql command
+var sqlCmd = &cobra.Command{
+	Use:   "sql",
+	Short: "Commands for administering the mappings that MirrorCat reads from a SQL database.",
+}
+
+// DefaultSQLDriver is the `database/sql` driver used to open "sql-dsn", if one is not specified by the invoker of
+// MirrorCat. It stores mappings in a file on the local disk, which is convenient for a single instance of MirrorCat.
+const DefaultSQLDriver = "sqlite3"
+
+func init() {
+	RootCmd.AddCommand(sqlCmd)
+
+	sqlCmd.PersistentFlags().String("sql-driver", "", "The driver used to open --sql-dsn, either \"postgres\" or \"sqlite3\". Defaults to the same driver \"mirrorcat start\" would use.")
+	sqlCmd.PersistentFlags().String("sql-dsn", "", "The database to administer. Defaults to the same database \"mirrorcat start\" would use.")
+}
+
+// connectSQL opens the database that "mirrorcat start" would use, unless the "--sql-driver" or "--sql-dsn" flags
+// say otherwise. Any migrations that have not yet been applied are applied before it is returned, and counted.
+func connectSQL(cmd *cobra.Command) (db *sql.DB, applied int, err error) {
+	driver, _ := cmd.Flags().GetString("sql-driver")
+	if driver == "" {
+		driver = viper.GetString("sql-driver")
+	}
+
+	dsn, _ := cmd.Flags().GetString("sql-dsn")
+	if dsn == "" {
+		dsn = viper.GetString("sql-dsn")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	return openSQL(ctx, driver, dsn)
+}
+
+// openSQL opens a database, and applies any migrations that it is missing. The number of migrations that were
+// applied is returned alongside it.
+func openSQL(ctx context.Context, driver, dsn string) (db *sql.DB, applied int, err error) {
+	if dsn == "" {
+		err = errors.New("no database was provided, see --sql-dsn")
+		return
+	}
+	if driver == "" {
+		driver = DefaultSQLDriver
+	}
+
+	if db, err = sql.Open(driver, dsn); err != nil {
+		return
+	}
+
+	if err = db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, 0, err
+	}
+
+	if applied, err = mirrorcat.MigrateSQL(ctx, db); err != nil {
+		db.Close()
+		return nil, 0, err
+	}
+	return
+}
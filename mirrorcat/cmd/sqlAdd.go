@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Azure/mirrorcat"
+	"github.com/spf13/cobra"
+)
+
+// sqlAddCmd represents the sql add command
+var sqlAddCmd = &cobra.Command{
+	Use:   "add {original repository} {original ref} {mirror repository} {mirror ref}",
+	Short: "Stores a mapping in a SQL database.",
+	Long: `Stores a mapping in a SQL database, so that each commit pushed to the original ref is
+also pushed to the mirror ref. Both repositories and refs are validated before anything
+is written.
+
+If the mapping already exists, its owner and comment are replaced.`,
+	Args: cobra.ExactArgs(4),
+	Run: func(cmd *cobra.Command, args []string) {
+		m, err := mappingFromArgs(args)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+
+		db, _, err := connectSQL(cmd)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+		defer db.Close()
+
+		entry := mirrorcat.SQLMapping{Mapping: m}
+		entry.Exclusion, _ = cmd.Flags().GetBool("exclusion")
+		entry.Owner, _ = cmd.Flags().GetString("owner")
+		entry.Comment, _ = cmd.Flags().GetString("comment")
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		if err = mirrorcat.SaveSQLMapping(ctx, db, entry); err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+		fmt.Printf("Added %s -> %s\n", mirrorcat.RedisRemoteRef(m.Original), mirrorcat.RedisRemoteRef(m.Mirror))
+	},
+}
+
+func init() {
+	sqlCmd.AddCommand(sqlAddCmd)
+
+	sqlAddCmd.Flags().String("owner", "", "Who is responsible for the mirror.")
+	sqlAddCmd.Flags().String("comment", "", "Why the mirror exists.")
+	sqlAddCmd.Flags().Bool("exclusion", false, "Store an exclusion, which prevents the mirror from being pushed to, rather than a mapping.")
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Azure/mirrorcat"
+	"github.com/spf13/cobra"
+)
+
+// sqlListCmd represents the sql list command
+var sqlListCmd = &cobra.Command{
+	Use:   "list [original repository [original ref]]",
+	Short: "Prints the mappings stored in a SQL database.",
+	Long: `Prints each mapping stored in a SQL database, along with who owns it and why it exists.
+Providing an original repository, and optionally a ref, only prints the mappings of that original.`,
+	Args: cobra.MaximumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		format, _ := cmd.Flags().GetString("output")
+		if format != "text" && format != "json" {
+			fmt.Fprintf(os.Stderr, "unrecognized output format %q\n", format)
+			os.Exit(1)
+		}
+
+		db, _, err := connectSQL(cmd)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+		defer db.Close()
+
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		found, err := mirrorcat.ReadSQLMappings(ctx, db)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+
+		exclusions, _ := cmd.Flags().GetBool("exclusions")
+		listed := make([]mirrorcat.SQLMapping, 0, len(found))
+		for _, m := range found {
+			if m.Exclusion != exclusions {
+				continue
+			}
+			if len(args) > 0 && m.Original.Repository != args[0] {
+				continue
+			}
+			if len(args) > 1 && m.Original.Ref != mirrorcat.NormalizeRef(args[1]) {
+				continue
+			}
+			listed = append(listed, m)
+		}
+
+		if format == "json" {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			encoder.Encode(listed)
+			return
+		}
+
+		for _, m := range listed {
+			details := []string{"created=" + m.Created.Format(time.RFC3339)}
+			if m.Owner != "" {
+				details = append(details, "owner="+m.Owner)
+			}
+			if m.Comment != "" {
+				details = append(details, fmt.Sprintf("comment=%q", m.Comment))
+			}
+			fmt.Printf("%s -> %s [%s]\n", mirrorcat.RedisRemoteRef(m.Original), mirrorcat.RedisRemoteRef(m.Mirror), strings.Join(details, " "))
+		}
+	},
+}
+
+func init() {
+	sqlCmd.AddCommand(sqlListCmd)
+
+	sqlListCmd.Flags().Bool("exclusions", false, "Print exclusions, rather than mappings.")
+	sqlListCmd.Flags().Duration("timeout", time.Minute, "The longest amount of time to spend reading from the database.")
+	sqlListCmd.Flags().StringP("output", "o", "text", "The format of the list that is written. Either \"text\" or \"json\".")
+}
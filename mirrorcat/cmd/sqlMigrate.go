@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// sqlMigrateCmd represents the sql migrate command
+var sqlMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Creates, or updates, the tables that MirrorCat reads mappings from.",
+	Long: `Applies each schema migration which has not yet been applied to the database.
+"mirrorcat start" and the other "mirrorcat sql" commands also do this before using
+the database, so this command is only needed to prepare a database ahead of time.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		db, applied, err := connectSQL(cmd)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+		defer db.Close()
+
+		fmt.Printf("Applied %d migrations.\n", applied)
+	},
+}
+
+func init() {
+	sqlCmd.AddCommand(sqlMigrateCmd)
+}
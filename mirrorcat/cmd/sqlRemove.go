@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Azure/mirrorcat"
+	"github.com/spf13/cobra"
+)
+
+// sqlRemoveCmd represents the sql remove command
+var sqlRemoveCmd = &cobra.Command{
+	Use:   "remove {original repository} {original ref} {mirror repository} {mirror ref}",
+	Short: "Deletes a mapping from a SQL database.",
+	Long: `Deletes a mapping from a SQL database. The command exits with a non-zero status if
+there was no such mapping.`,
+	Args: cobra.ExactArgs(4),
+	Run: func(cmd *cobra.Command, args []string) {
+		m, err := mappingFromArgs(args)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+
+		db, _, err := connectSQL(cmd)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+		defer db.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		exclusion, _ := cmd.Flags().GetBool("exclusion")
+		found, err := mirrorcat.DeleteSQLMapping(ctx, db, m, exclusion)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+
+		if !found {
+			fmt.Fprintf(os.Stderr, "No mapping from %s to %s was found\n", mirrorcat.RedisRemoteRef(m.Original), mirrorcat.RedisRemoteRef(m.Mirror))
+			os.Exit(1)
+		}
+		fmt.Printf("Removed %s -> %s\n", mirrorcat.RedisRemoteRef(m.Original), mirrorcat.RedisRemoteRef(m.Mirror))
+	},
+}
+
+func init() {
+	sqlCmd.AddCommand(sqlRemoveCmd)
+
+	sqlRemoveCmd.Flags().Bool("exclusion", false, "Remove an exclusion, rather than a mapping.")
+}
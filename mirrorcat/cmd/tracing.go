@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// DefaultTraceSampleRatio is the fraction of webhooks which are traced when "otlp-endpoint" is set, if not specified
+// by the invoker of MirrorCat. Webhooks which arrive as part of a sampled trace are always traced.
+const DefaultTraceSampleRatio = 1.0
+
+// traceContext reads the trace that a webhook belongs to from its "traceparent" and "baggage" headers.
+var traceContext = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+// configureTracing exports MirrorCat's spans to the OTLP collector at "otlp-endpoint", if it is set. The returned
+// function sends any spans which have not yet been exported, and must be called before MirrorCat exits.
+func configureTracing(host string) (flush func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(traceContext)
+
+	endpoint := viper.GetString("otlp-endpoint")
+	if endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(context.Background(), otlptracehttp.WithEndpointURL(endpoint))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(viper.GetFloat64("trace-sample-ratio")))),
+		sdktrace.WithResource(resource.NewSchemaless(
+			attribute.String("service.name", "mirrorcat"),
+			attribute.String("host.name", host),
+		)),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
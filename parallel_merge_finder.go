@@ -0,0 +1,228 @@
+package mirrorcat
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+)
+
+// ErrorPolicy determines how a ParallelMergeFinder reacts when one of its children fails.
+type ErrorPolicy int
+
+// These are the ErrorPolicies that a ParallelMergeFinder understands.
+const (
+	// FailFast stops every child as soon as any one of them fails, and returns that child's error.
+	FailFast ErrorPolicy = iota
+
+	// BestEffort allows every child to finish, publishing all results that could be found. If any
+	// child failed, a MergeError describing each failure is returned.
+	BestEffort
+)
+
+// ParseErrorPolicy reads the name of an ErrorPolicy, either "fail-fast" or "best-effort".
+func ParseErrorPolicy(name string) (ErrorPolicy, error) {
+	switch name {
+	case "fail-fast":
+		return FailFast, nil
+	case "best-effort":
+		return BestEffort, nil
+	default:
+		return FailFast, fmt.Errorf("%q is not a recognized error policy", name)
+	}
+}
+
+func (ep ErrorPolicy) String() string {
+	switch ep {
+	case FailFast:
+		return "fail-fast"
+	case BestEffort:
+		return "best-effort"
+	default:
+		return fmt.Sprintf("ErrorPolicy(%d)", int(ep))
+	}
+}
+
+// MergeError aggregates the errors encountered by each child of a ParallelMergeFinder that failed.
+type MergeError []error
+
+func (me MergeError) Error() string {
+	builder := &bytes.Buffer{}
+	fmt.Fprintf(builder, "%d of the merged MirrorFinders failed:", len(me))
+	for _, err := range me {
+		fmt.Fprint(builder, "\n\t", err.Error())
+	}
+	return builder.String()
+}
+
+// ParallelMergeFinder finds mirror mappings from multiple underlying MirrorFinders, by querying each of them
+// concurrently. Unlike MergeFinder, a mirror that is found by more than one child is only published once.
+type ParallelMergeFinder struct {
+	Finders []MirrorFinder
+	OnError ErrorPolicy
+}
+
+// FindMirrors queries all children at the same time, and publishes each distinct mirror as soon as it is found.
+//
+// If children have different priorities, see `Prioritized`, only those of equal priority are queried at the
+// same time. Exclusions and overrides are honored in the same way as a MergeFinder. Even with BestEffort, if the
+// exclusions of a tier can't be found, no mirrors are published from that tier or any lower one.
+func (pmf ParallelMergeFinder) FindMirrors(ctx context.Context, needle RemoteRef, results chan<- RemoteRef) error {
+	defer close(results)
+
+	seen := make(map[RemoteRef]struct{})
+	excluded := make(map[RemoteRef]struct{})
+	var failures MergeError
+
+	for _, tier := range tiersOf(pmf.Finders) {
+		if err := findExclusions(ctx, tier, needle, excluded); err != nil {
+			if pmf.OnError == FailFast {
+				return err
+			}
+			// Without its exclusions, neither this tier nor any lower tier can be trusted not to publish a mirror
+			// that should have been excluded.
+			failures = append(failures, err)
+			break
+		}
+
+		overridden, tierFailures, err := pmf.findTier(ctx, tier, needle, results, seen, excluded)
+		if err != nil {
+			return err
+		}
+		failures = append(failures, tierFailures...)
+
+		if overridden {
+			break
+		}
+	}
+
+	if len(failures) > 0 {
+		return failures
+	}
+	return nil
+}
+
+// findTier queries each of `tier` concurrently, publishing each mirror that hasn't already been seen or excluded.
+func (pmf ParallelMergeFinder) findTier(ctx context.Context, tier []MirrorFinder, needle RemoteRef, results chan<- RemoteRef, seen, excluded map[RemoteRef]struct{}) (overridden bool, failures MergeError, err error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type found struct {
+		RemoteRef
+		finder MirrorFinder
+		err    error
+		done   bool
+	}
+
+	merged := make(chan found)
+	var children sync.WaitGroup
+
+	for _, finder := range tier {
+		children.Add(1)
+		go func(finder MirrorFinder) {
+			defer children.Done()
+
+			intermediate := make(chan RemoteRef)
+			errs := make(chan error, 1)
+			go func() {
+				errs <- findMirrorsTraced(ctx, finder, needle, intermediate)
+			}()
+
+			for mirror := range intermediate {
+				select {
+				case merged <- found{RemoteRef: mirror, finder: finder}:
+					// Intentionally Left Blank
+				case <-ctx.Done():
+				}
+			}
+
+			select {
+			case merged <- found{finder: finder, err: <-errs, done: true}:
+				// Intentionally Left Blank
+			case <-ctx.Done():
+			}
+		}(finder)
+	}
+
+	go func() {
+		children.Wait()
+		close(merged)
+	}()
+
+	drain := func() {
+		cancel()
+		for range merged {
+			// Drain the remaining children so that none of them are leaked.
+		}
+	}
+
+	for item := range merged {
+		if item.done {
+			if item.err == nil {
+				continue
+			}
+
+			if pmf.OnError == FailFast {
+				drain()
+				err = item.err
+				return
+			}
+			failures = append(failures, item.err)
+			continue
+		}
+
+		if overrides(item.finder) {
+			overridden = true
+		}
+
+		key := item.RemoteRef.Canonical()
+		if _, ok := excluded[key]; ok {
+			continue
+		}
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+
+		select {
+		case results <- item.RemoteRef:
+			// Intentionally Left Blank
+		case <-ctx.Done():
+			drain()
+			err = ctx.Err()
+			return
+		}
+	}
+
+	err = ctx.Err()
+	return
+}
+
+// ListMirrors enumerates each child which is also a MirrorLister, publishing each distinct mapping once.
+func (pmf ParallelMergeFinder) ListMirrors(ctx context.Context, results chan<- Mapping) error {
+	defer close(results)
+
+	intermediate := make(chan Mapping)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- MergeFinder(pmf.Finders).ListMirrors(ctx, intermediate)
+	}()
+
+	seen := make(map[Mapping]struct{})
+	for mapping := range intermediate {
+		key := mapping.canonical()
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+
+		select {
+		case results <- mapping:
+			// Intentionally Left Blank
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return <-errs
+}
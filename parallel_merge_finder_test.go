@@ -0,0 +1,222 @@
+package mirrorcat_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/mirrorcat"
+)
+
+// failingFinder is a MirrorFinder that publishes a fixed set of mirrors, then fails.
+type failingFinder struct {
+	mirrors []mirrorcat.RemoteRef
+	err     error
+}
+
+func (ff failingFinder) FindMirrors(ctx context.Context, original mirrorcat.RemoteRef, results chan<- mirrorcat.RemoteRef) error {
+	defer close(results)
+	for _, m := range ff.mirrors {
+		select {
+		case results <- m:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return ff.err
+}
+
+// stuckFinder is a MirrorFinder that never finds anything, and only returns once it has been cancelled.
+type stuckFinder struct{}
+
+func (stuckFinder) FindMirrors(ctx context.Context, original mirrorcat.RemoteRef, results chan<- mirrorcat.RemoteRef) error {
+	defer close(results)
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// failingExcluder is a MirrorFinder whose exclusions can never be found.
+type failingExcluder struct {
+	mirrorcat.MirrorFinder
+	err error
+}
+
+func (fe failingExcluder) FindExclusions(ctx context.Context, original mirrorcat.RemoteRef, results chan<- mirrorcat.RemoteRef) error {
+	close(results)
+	return fe.err
+}
+
+func collectMirrors(ctx context.Context, finder mirrorcat.MirrorFinder, original mirrorcat.RemoteRef) (found []string, err error) {
+	results, errs := make(chan mirrorcat.RemoteRef), make(chan error, 1)
+	go func() {
+		errs <- finder.FindMirrors(ctx, original, results)
+	}()
+
+	for result := range results {
+		found = append(found, fmt.Sprintf("%s:%s", result.Repository, result.Ref))
+	}
+	sort.Strings(found)
+	err = <-errs
+	return
+}
+
+func ExampleParallelMergeFinder_FindMirrors() {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	const mainRepo = "github.com/Azure/mirrorcat"
+	const secondaryRepo = "github.com/marstr/mirrorcat"
+
+	child1, child2 := mirrorcat.NewDefaultMirrorFinder(), mirrorcat.NewDefaultMirrorFinder()
+
+	orig := mirrorcat.RemoteRef{Repository: mainRepo, Ref: "master"}
+
+	child1.AddMirrors(orig, mirrorcat.RemoteRef{Repository: secondaryRepo, Ref: "master"})
+	child2.AddMirrors(orig, mirrorcat.RemoteRef{Repository: secondaryRepo, Ref: "master"})
+
+	subject := mirrorcat.ParallelMergeFinder{
+		Finders: []mirrorcat.MirrorFinder{child1, child2},
+	}
+
+	found, err := collectMirrors(ctx, subject, orig)
+	fmt.Println(found)
+	fmt.Println(err)
+
+	// Output:
+	// [github.com/marstr/mirrorcat:master]
+	// <nil>
+}
+
+func TestParallelMergeFinder_FindMirrors_BestEffort(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	orig := mirrorcat.RemoteRef{Repository: "github.com/Azure/mirrorcat", Ref: "master"}
+
+	healthy := mirrorcat.NewDefaultMirrorFinder()
+	healthy.AddMirrors(orig, mirrorcat.RemoteRef{Repository: "github.com/marstr/mirrorcat", Ref: "master"})
+
+	subject := mirrorcat.ParallelMergeFinder{
+		Finders: []mirrorcat.MirrorFinder{
+			healthy,
+			failingFinder{
+				mirrors: []mirrorcat.RemoteRef{{Repository: "github.com/haydenmc/mirrorcat", Ref: "master"}},
+				err:     errors.New("first failure"),
+			},
+			failingFinder{err: errors.New("second failure")},
+		},
+		OnError: mirrorcat.BestEffort,
+	}
+
+	found, err := collectMirrors(ctx, subject, orig)
+
+	if want := "[github.com/haydenmc/mirrorcat:master github.com/marstr/mirrorcat:master]"; fmt.Sprint(found) != want {
+		t.Logf("\ngot:  %v\nwant: %v", found, want)
+		t.Fail()
+	}
+
+	merged, ok := err.(mirrorcat.MergeError)
+	if !ok {
+		t.Fatalf("expected a MergeError, got: %v", err)
+	}
+
+	if len(merged) != 2 {
+		t.Logf("got: %d errors want: 2", len(merged))
+		t.Fail()
+	}
+
+	if msg := merged.Error(); !strings.Contains(msg, "first failure") || !strings.Contains(msg, "second failure") {
+		t.Logf("error message didn't describe both failures: %s", msg)
+		t.Fail()
+	}
+}
+
+func TestParallelMergeFinder_FindMirrors_BestEffortExclusions(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	orig := mirrorcat.RemoteRef{Repository: "github.com/Azure/mirrorcat", Ref: "master"}
+
+	high, middle, low := mirrorcat.NewDefaultMirrorFinder(), mirrorcat.NewDefaultMirrorFinder(), mirrorcat.NewDefaultMirrorFinder()
+	high.AddMirrors(orig, mirrorcat.RemoteRef{Repository: "github.com/marstr/mirrorcat", Ref: "master"})
+	middle.AddMirrors(orig, mirrorcat.RemoteRef{Repository: "github.com/haydenmc/mirrorcat", Ref: "master"})
+	low.AddMirrors(orig, mirrorcat.RemoteRef{Repository: "github.com/Azure/azure-sdk-for-go", Ref: "master"})
+
+	subject := mirrorcat.ParallelMergeFinder{
+		Finders: []mirrorcat.MirrorFinder{
+			mirrorcat.PriorityFinder{MirrorFinder: high, Level: 2},
+			mirrorcat.PriorityFinder{MirrorFinder: failingExcluder{MirrorFinder: middle, err: errors.New("unreachable")}, Level: 1},
+			mirrorcat.PriorityFinder{MirrorFinder: low, Level: 0},
+		},
+		OnError: mirrorcat.BestEffort,
+	}
+
+	found, err := collectMirrors(ctx, subject, orig)
+
+	// The exclusions found in the middle tier could have applied to it, or to the tier below it.
+	if want := "[github.com/marstr/mirrorcat:master]"; fmt.Sprint(found) != want {
+		t.Logf("\ngot:  %v\nwant: %v", found, want)
+		t.Fail()
+	}
+
+	if merged, ok := err.(mirrorcat.MergeError); !ok || len(merged) != 1 {
+		t.Errorf("got: %v want: a MergeError describing the missing exclusions", err)
+	}
+}
+
+func TestParallelMergeFinder_FindMirrors_FailFast(t *testing.T) {
+	outside, cancelOutside := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancelOutside()
+
+	orig := mirrorcat.RemoteRef{Repository: "github.com/Azure/mirrorcat", Ref: "master"}
+
+	expected := errors.New("failure")
+	subject := mirrorcat.ParallelMergeFinder{
+		Finders: []mirrorcat.MirrorFinder{stuckFinder{}, failingFinder{err: expected}},
+		OnError: mirrorcat.FailFast,
+	}
+
+	_, err := collectMirrors(outside, subject, orig)
+	if err != expected {
+		t.Logf("got: %v want: %v", err, expected)
+		t.Fail()
+	}
+
+	if outside.Err() != nil {
+		t.Error("timed out waiting for the stuck child to be cancelled")
+	}
+}
+
+func TestParallelMergeFinder_FindMirrors_RespectsCancel(t *testing.T) {
+	outside, cancelOutside := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancelOutside()
+
+	inside, cancelInside := context.WithCancel(outside)
+
+	subject := mirrorcat.ParallelMergeFinder{
+		Finders: []mirrorcat.MirrorFinder{stuckFinder{}, stuckFinder{}},
+		OnError: mirrorcat.BestEffort,
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := collectMirrors(inside, subject, mirrorcat.RemoteRef{})
+		errs <- err
+	}()
+
+	cancelInside()
+
+	select {
+	case err := <-errs:
+		if err == nil || !strings.Contains(err.Error(), "cancel") {
+			t.Logf("expected a cancellation error, got: %v", err)
+			t.Fail()
+		}
+	case <-outside.Done():
+		t.Error("timed out")
+	}
+}
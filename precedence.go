@@ -0,0 +1,118 @@
+package mirrorcat
+
+import (
+	"context"
+	"sort"
+)
+
+// Prioritized is implemented by MirrorFinders whose results should take precedence over others when they
+// are combined by a MergeFinder or ParallelMergeFinder. MirrorFinders that do not implement it have a
+// priority of zero, and never override others.
+type Prioritized interface {
+	// Priority determines the order in which children are consulted, highest first.
+	Priority() int
+
+	// Overrides reports whether finding any mirrors for an original should hide the mirrors
+	// found for it by MirrorFinders with a lower priority.
+	Overrides() bool
+}
+
+// ExclusionFinder is implemented by MirrorFinders that are able to declare that a mirror should NOT be
+// pushed to, even if a MirrorFinder with an equal or lower priority says that it should.
+//
+// Just like `FindMirrors`, implementations must close the results channel once all exclusions have been published.
+type ExclusionFinder interface {
+	FindExclusions(context.Context, RemoteRef, chan<- RemoteRef) error
+}
+
+// PriorityFinder decorates a MirrorFinder with a priority, for use as a child of a MergeFinder.
+type PriorityFinder struct {
+	MirrorFinder
+	Level    int
+	Override bool
+}
+
+// Priority fetches the level at which this finder's results take precedence.
+func (pf PriorityFinder) Priority() int {
+	return pf.Level
+}
+
+// Overrides reports whether mirrors found by this finder hide those found by finders with a lower priority.
+func (pf PriorityFinder) Overrides() bool {
+	return pf.Override
+}
+
+// FindExclusions passes along the exclusions of the decorated MirrorFinder, if it is an ExclusionFinder.
+func (pf PriorityFinder) FindExclusions(ctx context.Context, original RemoteRef, results chan<- RemoteRef) error {
+	if excluder, ok := pf.MirrorFinder.(ExclusionFinder); ok {
+		return excluder.FindExclusions(ctx, original, results)
+	}
+	close(results)
+	return nil
+}
+
+// ListMirrors passes along the mappings of the decorated MirrorFinder, if it is a MirrorLister.
+func (pf PriorityFinder) ListMirrors(ctx context.Context, results chan<- Mapping) error {
+	if lister, ok := pf.MirrorFinder.(MirrorLister); ok {
+		return lister.ListMirrors(ctx, results)
+	}
+	close(results)
+	return nil
+}
+
+func priorityOf(finder MirrorFinder) int {
+	if p, ok := finder.(Prioritized); ok {
+		return p.Priority()
+	}
+	return 0
+}
+
+func overrides(finder MirrorFinder) bool {
+	if p, ok := finder.(Prioritized); ok {
+		return p.Overrides()
+	}
+	return false
+}
+
+// tiersOf groups MirrorFinders with the same priority together, ordered from highest to lowest priority.
+// Within a tier, MirrorFinders keep the order that they were provided in.
+func tiersOf(finders []MirrorFinder) (tiers [][]MirrorFinder) {
+	sorted := append([]MirrorFinder(nil), finders...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return priorityOf(sorted[i]) > priorityOf(sorted[j])
+	})
+
+	for i, finder := range sorted {
+		if i == 0 || priorityOf(finder) != priorityOf(sorted[i-1]) {
+			tiers = append(tiers, nil)
+		}
+		tiers[len(tiers)-1] = append(tiers[len(tiers)-1], finder)
+	}
+	return
+}
+
+// findExclusions asks each ExclusionFinder in `finders` which mirrors of `original` should be ignored, and adds
+// their canonical form to `excluded`.
+func findExclusions(ctx context.Context, finders []MirrorFinder, original RemoteRef, excluded map[RemoteRef]struct{}) error {
+	for _, finder := range finders {
+		excluder, ok := finder.(ExclusionFinder)
+		if !ok {
+			continue
+		}
+
+		intermediate := make(chan RemoteRef)
+		errs := make(chan error, 1)
+		go func() {
+			errs <- excluder.FindExclusions(ctx, original, intermediate)
+		}()
+
+		for exclusion := range intermediate {
+			excluded[exclusion.Canonical()] = struct{}{}
+		}
+
+		if err := <-errs; err != nil {
+			return err
+		}
+	}
+	return nil
+}
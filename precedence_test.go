@@ -0,0 +1,116 @@
+package mirrorcat_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Azure/mirrorcat"
+)
+
+func ExamplePriorityFinder() {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	orig := mirrorcat.RemoteRef{Repository: "github.com/Azure/mirrorcat", Ref: "master"}
+	dev := mirrorcat.RemoteRef{Repository: "github.com/Azure/mirrorcat", Ref: "dev"}
+	staging := mirrorcat.RemoteRef{Repository: "github.com/Azure/mirrorcat", Ref: "staging"}
+
+	static, dynamic := mirrorcat.NewDefaultMirrorFinder(), mirrorcat.NewDefaultMirrorFinder()
+	static.AddMirrors(orig, dev)
+	dynamic.AddMirrors(orig, staging)
+	dynamic.AddExclusions(orig, dev)
+
+	subject := mirrorcat.MergeFinder{
+		mirrorcat.PriorityFinder{MirrorFinder: static, Level: 0},
+		mirrorcat.PriorityFinder{MirrorFinder: dynamic, Level: 1},
+	}
+
+	found, err := collectMirrors(ctx, subject, orig)
+	fmt.Println(found)
+	fmt.Println(err)
+
+	// Output:
+	// [github.com/Azure/mirrorcat:staging]
+	// <nil>
+}
+
+func TestMergeFinder_FindMirrors_Precedence(t *testing.T) {
+	orig := mirrorcat.RemoteRef{Repository: "github.com/Azure/mirrorcat", Ref: "master"}
+	dev := mirrorcat.RemoteRef{Repository: "github.com/Azure/mirrorcat", Ref: "dev"}
+	staging := mirrorcat.RemoteRef{Repository: "github.com/Azure/mirrorcat", Ref: "staging"}
+
+	static, dynamic := mirrorcat.NewDefaultMirrorFinder(), mirrorcat.NewDefaultMirrorFinder()
+	static.AddMirrors(orig, dev)
+	static.AddExclusions(orig, staging)
+	dynamic.AddMirrors(orig, staging)
+
+	testCases := []struct {
+		name     string
+		children []mirrorcat.MirrorFinder
+		want     string
+	}{
+		{
+			"static excludes lower",
+			[]mirrorcat.MirrorFinder{
+				mirrorcat.PriorityFinder{MirrorFinder: static, Level: 1},
+				mirrorcat.PriorityFinder{MirrorFinder: dynamic, Level: 0},
+			},
+			"[github.com/Azure/mirrorcat:dev]",
+		},
+		{
+			"static cannot exclude higher",
+			[]mirrorcat.MirrorFinder{
+				mirrorcat.PriorityFinder{MirrorFinder: static, Level: 0},
+				mirrorcat.PriorityFinder{MirrorFinder: dynamic, Level: 1},
+			},
+			"[github.com/Azure/mirrorcat:dev github.com/Azure/mirrorcat:staging]",
+		},
+		{
+			"equal priority exclusions apply",
+			[]mirrorcat.MirrorFinder{dynamic, static},
+			"[github.com/Azure/mirrorcat:dev]",
+		},
+		{
+			"override hides lower",
+			[]mirrorcat.MirrorFinder{
+				mirrorcat.PriorityFinder{MirrorFinder: static, Level: 0},
+				mirrorcat.PriorityFinder{MirrorFinder: dynamic, Level: 1, Override: true},
+			},
+			"[github.com/Azure/mirrorcat:staging]",
+		},
+		{
+			"override without mirrors",
+			[]mirrorcat.MirrorFinder{
+				mirrorcat.PriorityFinder{MirrorFinder: static, Level: 0},
+				mirrorcat.PriorityFinder{MirrorFinder: mirrorcat.NewDefaultMirrorFinder(), Level: 1, Override: true},
+			},
+			"[github.com/Azure/mirrorcat:dev]",
+		},
+	}
+
+	for _, tc := range testCases {
+		subjects := map[string]mirrorcat.MirrorFinder{
+			"sequential": mirrorcat.MergeFinder(tc.children),
+			"parallel":   mirrorcat.ParallelMergeFinder{Finders: tc.children},
+		}
+
+		for strategy, subject := range subjects {
+			t.Run(fmt.Sprintf("%s/%s", tc.name, strategy), func(t *testing.T) {
+				ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+				defer cancel()
+
+				found, err := collectMirrors(ctx, subject, orig)
+				if err != nil {
+					t.Error(err)
+				}
+
+				if got := fmt.Sprint(found); got != tc.want {
+					t.Logf("\ngot:  %s\nwant: %s", got, tc.want)
+					t.Fail()
+				}
+			})
+		}
+	}
+}
@@ -3,13 +3,17 @@ package mirrorcat
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"os/exec"
 	"strings"
+	"time"
 
 	homedir "github.com/mitchellh/go-homedir"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // PushEvent encapsulates all data that will be provided by a GitHub Webhook PushEvent.
@@ -80,23 +84,151 @@ func (ce CmdErr) Error() string {
 	return builder.String()
 }
 
+// MirrorOptions customize how MirrorCat pushes to a single mirror.
+type MirrorOptions struct {
+	// Depth is the number of commits that this mirror needs to be cloned. When it is larger than the depth
+	// that would otherwise be used, the original is cloned more deeply. Zero means no preference.
+	Depth int `json:"depth,omitempty"`
+
+	// Force allows the mirror's branch to be overwritten, even if it has commits that the original doesn't.
+	Force bool `json:"force,omitempty"`
+
+	// Credentials names the environment variable which holds the access token used to push to this mirror.
+	Credentials string `json:"credentials,omitempty"`
+
+	// Owner identifies who is responsible for this mirror. It is not interpreted by MirrorCat.
+	Owner string `json:"owner,omitempty"`
+
+	// Expires, if not zero, is the time at which this mirror should be forgotten.
+	Expires time.Time `json:"expires,omitempty"`
+}
+
+// OptionsFinder is implemented by MirrorFinders that are able to store MirrorOptions alongside each mapping.
+type OptionsFinder interface {
+	FindOptions(Mapping) (MirrorOptions, error)
+}
+
 // Push clones the original repository, then pushes the branch specified to another repository.
 func Push(ctx context.Context, original, mirror RemoteRef, depth int) (err error) {
-	const mirrorRemoteHandle = "other"
+	return PushAll(ctx, original, []RemoteRef{mirror}, depth)[0]
+}
+
+// PushAll clones the original repository once, then pushes the branch specified to each of the mirrors.
+// The returned slice has one entry for each mirror, which is nil if that push succeeded.
+func PushAll(ctx context.Context, original RemoteRef, mirrors []RemoteRef, depth int) (errs []error) {
+	return PushAllWithOptions(ctx, original, mirrors, nil, depth)
+}
+
+// PushAllWithOptions behaves like PushAll, but honors the MirrorOptions at the same index as each mirror.
+// If `options` is shorter than `mirrors`, the remaining mirrors use the zero value of MirrorOptions.
+func PushAllWithOptions(ctx context.Context, original RemoteRef, mirrors []RemoteRef, options []MirrorOptions, depth int) (errs []error) {
+	results := PushAllWithResults(ctx, original, mirrors, options, depth)
+
+	errs = make([]error, len(results))
+	for i := range results {
+		errs[i] = results[i].Err
+	}
+	return
+}
+
+// PushResult describes how a single mirror was changed by PushAllWithResults.
+type PushResult struct {
+	// Before is the commit that the mirror's branch pointed to before it was pushed to. It is empty if the branch
+	// did not exist, or the mirror could not be reached.
+	Before string
+
+	// After is the commit that the mirror's branch points to once it has been pushed to. It is empty if the push
+	// failed.
+	After string
+
+	// Err is nil if the push succeeded.
+	Err error
+}
+
+// PushAllWithResults behaves like PushAllWithOptions, but also reports the commits that each mirror's branch pointed
+// to before and after it was pushed to.
+func PushAllWithResults(ctx context.Context, original RemoteRef, mirrors []RemoteRef, options []MirrorOptions, depth int) (results []PushResult) {
+	optionsOf := func(i int) MirrorOptions {
+		if i < len(options) {
+			return options[i]
+		}
+		return MirrorOptions{}
+	}
+
+	for i := range mirrors {
+		if requested := optionsOf(i).Depth; depth > 0 && requested > depth {
+			depth = requested
+		}
+	}
+
+	results = make([]PushResult, len(mirrors))
+
+	ctx, span := StartSpan(ctx, "Push", AttributeOriginal.String(LogRef(original)))
+
+	// Each mirror leaves the queue as soon as it has been pushed to, while any that remain once the clone has failed
+	// leave it together.
+	queued := len(mirrors)
+	PushQueueDepth.Add(float64(queued))
+	defer func() {
+		PushQueueDepth.Sub(float64(queued))
+
+		failures := 0
+		for i := range results {
+			Pushes.WithLabelValues(HostOf(mirrors[i].Repository), outcomeOf(results[i].Err)).Inc()
+			if results[i].Err != nil {
+				failures++
+			}
+		}
+
+		var err error
+		if failures > 0 {
+			err = fmt.Errorf("%d of %d mirrors were not pushed to", failures, len(results))
+		}
+		EndSpan(span, err)
+	}()
+
+	fail := func(err error) []PushResult {
+		for i := range results {
+			if results[i].Err == nil {
+				results[i].Err = err
+			}
+		}
+		return results
+	}
 
 	cloneLoc, err := ioutil.TempDir("", "mirrorcat")
 	if err != nil {
-		return
+		return fail(err)
 	}
 	defer os.RemoveAll(cloneLoc)
 
-	runCmd := func(cmd *exec.Cmd) (err error) {
+	logger := LoggerFrom(ctx).WithField(LogFieldOriginal, LogRef(original))
+
+	// runCmd logs the outcome of each stage with `stageLogger`, which identifies the mirror that it belongs to, if any.
+	// Each stage is traced in a span of its own, which is given `attributes`.
+	runCmd := func(stageLogger *logrus.Entry, stage string, cmd *exec.Cmd, attributes ...attribute.KeyValue) (err error) {
+		_, stageSpan := StartSpan(ctx, "git "+stage, append(attributes, AttributeStage.String(stage))...)
+		defer func() {
+			EndSpan(stageSpan, err)
+		}()
+
+		started := time.Now()
 		output, err := cmd.CombinedOutput()
+		elapsed := time.Since(started)
+		GitDuration.WithLabelValues(stage, outcomeOf(err)).Observe(elapsed.Seconds())
+
+		stageLogger = stageLogger.WithFields(logrus.Fields{
+			LogFieldStage: stage,
+			"duration":    elapsed.String(),
+		})
 		if err != nil {
 			err = CmdErr{
 				error:  err,
 				Output: output,
 			}
+			stageLogger.WithError(errors.New(RedactCredentials(err.Error()))).Error("Git failed")
+		} else {
+			stageLogger.Info("Git succeeded")
 		}
 		return
 	}
@@ -108,19 +240,80 @@ func Push(ctx context.Context, original, mirror RemoteRef, depth int) (err error
 		cloner.Args = append(cloner.Args[:2], clonerArgs...)
 	}
 	cloner.Dir, _ = homedir.Dir()
-	if err = runCmd(cloner); err != nil {
-		return
+	if err = runCmd(logger, "clone", cloner); err != nil {
+		return fail(err)
 	}
 
-	remoteAdder := exec.CommandContext(ctx, "git", "remote", "add", mirrorRemoteHandle, mirror.Repository)
-	remoteAdder.Dir = cloneLoc
-
-	if err = runCmd(remoteAdder); err != nil {
-		return
+	revParser := exec.CommandContext(ctx, "git", "rev-parse", "HEAD")
+	revParser.Dir = cloneLoc
+	head, err := revParser.Output()
+	if err != nil {
+		return fail(err)
 	}
 
-	pusher := exec.CommandContext(ctx, "git", "push", mirrorRemoteHandle, fmt.Sprintf("%s:%s", NormalizeRef(original.Ref), NormalizeRef(mirror.Ref)))
-	pusher.Dir = cloneLoc
-	err = runCmd(pusher)
+	for i, mirror := range mirrors {
+		mirrorRemoteHandle := fmt.Sprintf("other%d", i)
+		mirrorLogger := logger.WithField(LogFieldMirror, LogRef(mirror))
+		mirrorAttribute := AttributeMirror.String(LogRef(mirror))
+
+		remoteAdder := exec.CommandContext(ctx, "git", "remote", "add", mirrorRemoteHandle, mirror.Repository)
+		remoteAdder.Dir = cloneLoc
+
+		if results[i].Err = runCmd(mirrorLogger, "remote-add", remoteAdder, mirrorAttribute); results[i].Err != nil {
+			queued--
+			PushQueueDepth.Dec()
+			continue
+		}
+
+		// Failing to learn where the mirror's branch was is not fatal, if the mirror is unreachable the push
+		// will report it.
+		lister := exec.CommandContext(ctx, "git", "ls-remote", "--heads", mirrorRemoteHandle, "refs/heads/"+NormalizeRef(mirror.Ref))
+		lister.Dir = cloneLoc
+		listStarted := time.Now()
+		listed, err := lister.Output()
+		GitDuration.WithLabelValues("ls-remote", outcomeOf(err)).Observe(time.Since(listStarted).Seconds())
+		if err == nil {
+			if fields := strings.Fields(string(listed)); len(fields) > 0 {
+				results[i].Before = fields[0]
+			}
+		}
+
+		pushArgs := []string{"push"}
+		if optionsOf(i).Force {
+			pushArgs = append(pushArgs, "--force")
+		}
+		pushArgs = append(pushArgs, mirrorRemoteHandle, fmt.Sprintf("%s:%s", NormalizeRef(original.Ref), NormalizeRef(mirror.Ref)))
+
+		pusher := exec.CommandContext(ctx, "git", pushArgs...)
+		pusher.Dir = cloneLoc
+		if results[i].Err = runCmd(mirrorLogger, "push", pusher, mirrorAttribute); results[i].Err == nil {
+			results[i].After = strings.TrimSpace(string(head))
+		}
+		queued--
+		PushQueueDepth.Dec()
+	}
 	return
 }
+
+// LsRemote contacts a remote repository to ensure that it is reachable.
+// If `target.Ref` is not empty, the remote must also have a branch with that name.
+func LsRemote(ctx context.Context, target RemoteRef) error {
+	args := []string{"ls-remote", "--heads"}
+	if target.Ref == "" {
+		args = append(args, "--", target.Repository)
+	} else {
+		args = append(args, "--exit-code", "--", target.Repository, "refs/heads/"+NormalizeRef(target.Ref))
+	}
+
+	lister := exec.CommandContext(ctx, "git", args...)
+	lister.Dir, _ = homedir.Dir()
+
+	output, err := lister.CombinedOutput()
+	if err != nil {
+		return CmdErr{
+			error:  err,
+			Output: output,
+		}
+	}
+	return nil
+}
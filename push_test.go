@@ -9,6 +9,7 @@ import (
 	"os"
 	"os/exec"
 	"path"
+	"strings"
 	"testing"
 
 	"github.com/Azure/mirrorcat"
@@ -139,3 +140,129 @@ func TestPush(t *testing.T) {
 		return
 	}
 }
+
+func TestPushAll(t *testing.T) {
+	locPrefix, err := ioutil.TempDir("", "mirrorcat_test")
+	if err != nil {
+		t.Error()
+		t.FailNow()
+	}
+	defer os.RemoveAll(locPrefix)
+
+	originalLoc := path.Join(locPrefix, "leader")
+	followerLocs := []string{path.Join(locPrefix, "follower1"), path.Join(locPrefix, "follower2")}
+
+	runCmd := func(cmd *exec.Cmd) {
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Log(string(output))
+			t.Error(err)
+			t.FailNow()
+		}
+	}
+
+	runCmd(exec.Command("git", "init", originalLoc))
+	for _, loc := range followerLocs {
+		runCmd(exec.Command("git", "init", "--bare", loc))
+	}
+
+	err = ioutil.WriteFile(path.Join(originalLoc, "content.txt"), []byte("Hello World!!!"), os.ModePerm)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	adder := exec.Command("git", "add", "--all")
+	adder.Dir = originalLoc
+	runCmd(adder)
+
+	commiter := exec.Command("git", "commit", "-m", `"This is only a test."`)
+	commiter.Dir = originalLoc
+	runCmd(commiter)
+
+	original := mirrorcat.RemoteRef{
+		Repository: originalLoc,
+		Ref:        "master",
+	}
+
+	mirrors := []mirrorcat.RemoteRef{
+		{Repository: followerLocs[0], Ref: "master"},
+		{Repository: followerLocs[1], Ref: "dev"},
+		{Repository: path.Join(locPrefix, "nonexistent"), Ref: "master"},
+	}
+
+	errs := mirrorcat.PushAll(context.Background(), original, mirrors, -1)
+	if len(errs) != len(mirrors) {
+		t.Fatalf("got: %d errors want: %d", len(errs), len(mirrors))
+	}
+
+	for i, err := range errs[:2] {
+		if err != nil {
+			t.Errorf("unexpected error pushing to %v: %v", mirrors[i], err)
+			continue
+		}
+
+		if err = mirrorcat.LsRemote(context.Background(), mirrors[i]); err != nil {
+			t.Errorf("%v was not created: %v", mirrors[i], err)
+		}
+	}
+
+	if errs[2] == nil {
+		t.Error("expected pushing to a nonexistent repository to fail")
+	}
+}
+
+func TestPushAllWithResults(t *testing.T) {
+	locPrefix, err := ioutil.TempDir("", "mirrorcat_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(locPrefix)
+
+	originalLoc, mirrorLoc := path.Join(locPrefix, "leader"), path.Join(locPrefix, "follower")
+
+	runCmd := func(cmd *exec.Cmd) string {
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Log(string(output))
+			t.Fatal(err)
+		}
+		return strings.TrimSpace(string(output))
+	}
+
+	runCmd(exec.Command("git", "init", originalLoc))
+	runCmd(exec.Command("git", "init", "--bare", mirrorLoc))
+
+	commit := func(message string) string {
+		commiter := exec.Command("git", "commit", "--allow-empty", "-m", message)
+		commiter.Dir = originalLoc
+		runCmd(commiter)
+
+		revParser := exec.Command("git", "rev-parse", "HEAD")
+		revParser.Dir = originalLoc
+		return runCmd(revParser)
+	}
+
+	original := mirrorcat.RemoteRef{Repository: originalLoc, Ref: "master"}
+	mirrors := []mirrorcat.RemoteRef{{Repository: mirrorLoc, Ref: "master"}}
+
+	first := commit("first")
+	results := mirrorcat.PushAllWithResults(context.Background(), original, mirrors, nil, -1)
+	if results[0].Err != nil {
+		t.Fatal(results[0].Err)
+	}
+	if results[0].Before != "" || results[0].After != first {
+		t.Logf("got: %q..%q want: %q..%q", results[0].Before, results[0].After, "", first)
+		t.Fail()
+	}
+
+	second := commit("second")
+	results = mirrorcat.PushAllWithResults(context.Background(), original, mirrors, nil, -1)
+	if results[0].Err != nil {
+		t.Fatal(results[0].Err)
+	}
+	if results[0].Before != first || results[0].After != second {
+		t.Logf("got: %q..%q want: %q..%q", results[0].Before, results[0].After, first, second)
+		t.Fail()
+	}
+}
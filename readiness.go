@@ -0,0 +1,126 @@
+package mirrorcat
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+
+	homedir "github.com/mitchellh/go-homedir"
+)
+
+// ReadinessCheck is a single test of whether MirrorCat is able to do its job, like whether one of its dependencies
+// can be reached.
+type ReadinessCheck struct {
+	Name string
+
+	// Degradable is set for a dependency that MirrorCat can carry on without, like a source of mappings protected by a
+	// CircuitBreaker. While it fails, MirrorCat is degraded, rather than not ready.
+	Degradable bool
+
+	// Check returns an error if MirrorCat is not ready. Otherwise, it may return a short description of what it found,
+	// like the version of a dependency.
+	Check func(context.Context) (detail string, err error)
+}
+
+// CheckResult is the outcome of a single ReadinessCheck. `Degraded` is set when a Degradable check fails.
+type CheckResult struct {
+	Name     string `json:"name"`
+	Ready    bool   `json:"ready"`
+	Degraded bool   `json:"degraded,omitempty"`
+	Detail   string `json:"detail,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// ReadinessStatus summarizes a ReadinessReport.
+type ReadinessStatus string
+
+// These are the states that MirrorCat can report itself to be in.
+const (
+	ReadinessOK          ReadinessStatus = "ok"
+	ReadinessDegraded    ReadinessStatus = "degraded"
+	ReadinessUnavailable ReadinessStatus = "unavailable"
+	ReadinessDraining    ReadinessStatus = "draining"
+)
+
+// ReadinessReport is the outcome of a set of ReadinessChecks. It is `Ready` unless a check which isn't Degradable
+// failed, and is `ReadinessDegraded` while only Degradable checks are failing.
+type ReadinessReport struct {
+	Status ReadinessStatus `json:"status"`
+	Ready  bool            `json:"ready"`
+	Checks []CheckResult   `json:"checks"`
+}
+
+// CheckReadiness runs each of `checks` at the same time, and waits for all of them to finish. Results are reported in
+// the same order as `checks`.
+func CheckReadiness(ctx context.Context, checks []ReadinessCheck) ReadinessReport {
+	report := ReadinessReport{
+		Status: ReadinessOK,
+		Ready:  true,
+		Checks: make([]CheckResult, len(checks)),
+	}
+
+	var wg sync.WaitGroup
+	for i := range checks {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			detail, err := checks[i].Check(ctx)
+			report.Checks[i] = CheckResult{
+				Name:     checks[i].Name,
+				Ready:    err == nil,
+				Degraded: err != nil && checks[i].Degradable,
+				Detail:   detail,
+			}
+			if err != nil {
+				report.Checks[i].Error = RedactCredentials(err.Error())
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		if result.Degraded {
+			if report.Ready {
+				report.Status = ReadinessDegraded
+			}
+		} else if !result.Ready {
+			report.Ready = false
+			report.Status = ReadinessUnavailable
+		}
+	}
+	return report
+}
+
+// GitVersion finds the version of the git binary that MirrorCat pushes with, like "git version 2.17.1". An error is
+// returned if git cannot be found.
+func GitVersion(ctx context.Context) (string, error) {
+	versioner := exec.CommandContext(ctx, "git", "--version")
+	versioner.Dir, _ = homedir.Dir()
+
+	output, err := versioner.CombinedOutput()
+	if err != nil {
+		return "", CmdErr{
+			error:  err,
+			Output: output,
+		}
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// CheckFreeSpace ensures that at least `minBytes` are available to unprivileged users on the file system holding `dir`,
+// returning a description of the space that is available.
+func CheckFreeSpace(dir string, minBytes uint64) (string, error) {
+	available, err := FreeSpace(dir)
+	if err != nil {
+		return "", err
+	}
+
+	detail := fmt.Sprintf("%d MiB available in %s", available/(1024*1024), dir)
+	if available < minBytes {
+		return detail, fmt.Errorf("only %d MiB available in %s, at least %d MiB are needed", available/(1024*1024), dir, minBytes/(1024*1024))
+	}
+	return detail, nil
+}
@@ -0,0 +1,153 @@
+package mirrorcat
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// RedisRefEncoding determines how a RemoteRef is written into a Redis key or Set member.
+type RedisRefEncoding int
+
+// These are the RedisRefEncodings that MirrorCat understands. Both may be used side by side in the same Redis
+// instance, because every string written using RedisJSONEncoding starts with `RedisJSONPrefix`.
+const (
+	// RedisColonEncoding is the original format, `mirrorcat.RedisRemoteRef(target).String()`. It can't represent
+	// a ref which contains a colon, because everything after the first colon is assumed to be the repository.
+	RedisColonEncoding RedisRefEncoding = iota
+
+	// RedisJSONEncoding writes `RedisJSONPrefix`, followed by a RemoteRef marshaled as compact JSON. Any ref or
+	// repository survives a round trip through it.
+	RedisJSONEncoding
+)
+
+// RedisJSONPrefix marks a key, or Set member, which was written using RedisJSONEncoding.
+const RedisJSONPrefix = "json:"
+
+// redisRefEncodings lists every RedisRefEncoding, in the order they should be consulted.
+var redisRefEncodings = []RedisRefEncoding{RedisColonEncoding, RedisJSONEncoding}
+
+// ParseRedisRefEncoding reads the name of a RedisRefEncoding, either "colon" or "json".
+func ParseRedisRefEncoding(name string) (RedisRefEncoding, error) {
+	switch name {
+	case "colon":
+		return RedisColonEncoding, nil
+	case "json":
+		return RedisJSONEncoding, nil
+	default:
+		return RedisColonEncoding, fmt.Errorf("%q is not a recognized Redis encoding", name)
+	}
+}
+
+func (enc RedisRefEncoding) String() string {
+	switch enc {
+	case RedisColonEncoding:
+		return "colon"
+	case RedisJSONEncoding:
+		return "json"
+	default:
+		return fmt.Sprintf("RedisRefEncoding(%d)", int(enc))
+	}
+}
+
+// RedisEncodingFor chooses how the mirrors or exclusions of `original` are stored. RedisJSONEncoding is used when the
+// key of `original`, written using RedisColonEncoding, begins with one of `jsonPrefixes`. Otherwise RedisColonEncoding
+// is used, which every version of MirrorCat understands.
+func RedisEncodingFor(original RemoteRef, jsonPrefixes []string) RedisRefEncoding {
+	key := EncodeRedisRemoteRef(original, RedisColonEncoding)
+	for _, prefix := range jsonPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return RedisJSONEncoding
+		}
+	}
+	return RedisColonEncoding
+}
+
+// EncodeRedisRemoteRef writes `target` using the specified encoding.
+func EncodeRedisRemoteRef(target RemoteRef, enc RedisRefEncoding) string {
+	if enc != RedisJSONEncoding {
+		return RedisRemoteRef(target).String()
+	}
+
+	// Marshaling a struct of two strings can't fail.
+	marshaled, _ := json.Marshal(target)
+	return RedisJSONPrefix + string(marshaled)
+}
+
+// DecodeRedisRemoteRef reads a RemoteRef written using any RedisRefEncoding, and reports which one was used.
+func DecodeRedisRemoteRef(input string) (target RemoteRef, enc RedisRefEncoding, err error) {
+	if !isRedisJSON(input) {
+		var parsed RedisRemoteRef
+		parsed, err = ParseRedisRemoteRef(input)
+		return RemoteRef(parsed), RedisColonEncoding, err
+	}
+
+	enc = RedisJSONEncoding
+	body := strings.TrimPrefix(input, RedisJSONPrefix)
+	if jsonObjectEnd(body) != len(body) {
+		err = fmt.Errorf("%q does not resemble a JSON encoded RemoteRef", input)
+		return
+	}
+
+	if err = json.Unmarshal([]byte(body), &target); err != nil {
+		err = fmt.Errorf("%q does not resemble a JSON encoded RemoteRef: %v", input, err)
+	}
+	return
+}
+
+// splitRedisMappingKey separates the original and mirror halves of a version 2 mapping key, once its prefix has been
+// removed. The original is written with the same encoding as the mirror. When that encoding is RedisJSONEncoding,
+// the end of the original is found by reading a JSON object, so that spaces inside of it are not mistaken for the
+// separator.
+func splitRedisMappingKey(key string) (original, mirror string, ok bool) {
+	if !isRedisJSON(key) {
+		splitPoint := strings.Index(key, redisMappingSeparator)
+		if splitPoint < 0 {
+			return
+		}
+		return key[:splitPoint], key[splitPoint+len(redisMappingSeparator):], true
+	}
+
+	body := strings.TrimPrefix(key, RedisJSONPrefix)
+	end := jsonObjectEnd(body)
+	if end < 0 || !strings.HasPrefix(body[end:], redisMappingSeparator) {
+		return
+	}
+	return RedisJSONPrefix + body[:end], body[end+len(redisMappingSeparator):], true
+}
+
+// jsonObjectEnd finds the index just after the JSON object at the start of `input`, or -1 if `input` doesn't start
+// with a complete JSON object. Only the structure needed to find the closing brace is checked.
+func jsonObjectEnd(input string) int {
+	if !strings.HasPrefix(input, "{") {
+		return -1
+	}
+
+	depth := 0
+	inString, escaped := false, false
+
+	for i, r := range input {
+		switch {
+		case escaped:
+			escaped = false
+		case inString && r == '\\':
+			escaped = true
+		case r == '"':
+			inString = !inString
+		case inString:
+			// Intentionally Left Blank
+		case r == '{':
+			depth++
+		case r == '}':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return -1
+}
+
+func isRedisJSON(input string) bool {
+	return strings.HasPrefix(input, RedisJSONPrefix+"{")
+}
@@ -0,0 +1,173 @@
+package mirrorcat_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Azure/mirrorcat"
+)
+
+func ExampleEncodeRedisRemoteRef() {
+	subject := mirrorcat.RemoteRef{
+		Repository: "https://github.com/Azure/mirrorcat",
+		Ref:        "release:2018",
+	}
+
+	fmt.Println(mirrorcat.EncodeRedisRemoteRef(subject, mirrorcat.RedisColonEncoding))
+	fmt.Println(mirrorcat.EncodeRedisRemoteRef(subject, mirrorcat.RedisJSONEncoding))
+
+	// Output:
+	// release:2018:https://github.com/Azure/mirrorcat
+	// json:{"repo":"https://github.com/Azure/mirrorcat","ref":"release:2018"}
+}
+
+func TestDecodeRedisRemoteRef_RoundTrip(t *testing.T) {
+	testCases := []mirrorcat.RemoteRef{
+		{Repository: "https://github.com/Azure/mirrorcat", Ref: "master"},
+		{Repository: "https://github.com/Azure/mirrorcat", Ref: "release:2018"},
+		{Repository: "/path/with spaces/and:colons", Ref: "odd ref"},
+		{Repository: `quote"and\backslash`, Ref: "{braces}"},
+		{Repository: "", Ref: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run("", func(t *testing.T) {
+			encoded := mirrorcat.EncodeRedisRemoteRef(tc, mirrorcat.RedisJSONEncoding)
+			decoded, enc, err := mirrorcat.DecodeRedisRemoteRef(encoded)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if enc != mirrorcat.RedisJSONEncoding {
+				t.Logf("got: %v want: %v", enc, mirrorcat.RedisJSONEncoding)
+				t.Fail()
+			}
+
+			if decoded != tc {
+				t.Logf("got: %+v want: %+v", decoded, tc)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestDecodeRedisRemoteRef_Colon(t *testing.T) {
+	decoded, enc, err := mirrorcat.DecodeRedisRemoteRef("master:https://github.com/Azure/mirrorcat")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := mirrorcat.RemoteRef{Repository: "https://github.com/Azure/mirrorcat", Ref: "master"}
+	if decoded != want || enc != mirrorcat.RedisColonEncoding {
+		t.Logf("got: %+v (%v) want: %+v (%v)", decoded, enc, want, mirrorcat.RedisColonEncoding)
+		t.Fail()
+	}
+}
+
+func TestDecodeRedisRemoteRef_Invalid(t *testing.T) {
+	testCases := []string{
+		"",
+		`json:{"repo":"unterminated`,
+		`json:{"repo":"a","ref":"b"} trailing`,
+		`json:{"repo":1}`,
+	}
+
+	for _, tc := range testCases {
+		t.Run("", func(t *testing.T) {
+			if _, _, err := mirrorcat.DecodeRedisRemoteRef(tc); err == nil {
+				t.Log("expected a non-nil error for:", tc)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestRedisEncodingFor(t *testing.T) {
+	prefixes := []string{"release:", "dev:https://github.com/Azure/"}
+
+	testCases := []struct {
+		original mirrorcat.RemoteRef
+		want     mirrorcat.RedisRefEncoding
+	}{
+		{mirrorcat.RemoteRef{Repository: "https://github.com/Azure/mirrorcat", Ref: "release:2018"}, mirrorcat.RedisJSONEncoding},
+		{mirrorcat.RemoteRef{Repository: "https://github.com/Azure/mirrorcat", Ref: "dev"}, mirrorcat.RedisJSONEncoding},
+		{mirrorcat.RemoteRef{Repository: "https://github.com/marstr/mirrorcat", Ref: "dev"}, mirrorcat.RedisColonEncoding},
+		{mirrorcat.RemoteRef{Repository: "https://github.com/Azure/mirrorcat", Ref: "master"}, mirrorcat.RedisColonEncoding},
+	}
+
+	for _, tc := range testCases {
+		t.Run(mirrorcat.RedisRemoteRef(tc.original).String(), func(t *testing.T) {
+			if got := mirrorcat.RedisEncodingFor(tc.original, prefixes); got != tc.want {
+				t.Logf("got: %v want: %v", got, tc.want)
+				t.Fail()
+			}
+		})
+	}
+
+	if got := mirrorcat.RedisEncodingFor(testCases[0].original, nil); got != mirrorcat.RedisColonEncoding {
+		t.Logf("without prefixes got: %v want: %v", got, mirrorcat.RedisColonEncoding)
+		t.Fail()
+	}
+}
+
+func TestRedisFinder_FindMirrors_JSONEncoding(t *testing.T) {
+	client := connectTestRedis(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	original := mirrorcat.RemoteRef{Repository: "encodedRepo", Ref: "release:2018"}
+	legacy := mirrorcat.RemoteRef{Repository: "legacy mirror", Ref: "dev:1"}
+	indexed := mirrorcat.Mapping{Original: original, Mirror: mirrorcat.RemoteRef{Repository: "indexed mirror", Ref: "dev:2"}}
+
+	legacyKey := mirrorcat.EncodeRedisRemoteRef(original, mirrorcat.RedisJSONEncoding)
+	defer client.Del(legacyKey, mirrorcat.RedisIndexKey(original, mirrorcat.RedisJSONEncoding), mirrorcat.RedisMappingKey(indexed, mirrorcat.RedisJSONEncoding))
+
+	if err := client.SAdd(legacyKey, mirrorcat.EncodeRedisRemoteRef(legacy, mirrorcat.RedisJSONEncoding)).Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mirrorcat.SaveRedisMapping(client, indexed, mirrorcat.MirrorOptions{Depth: 1}, mirrorcat.RedisColonEncoding); err == nil {
+		t.Error("expected a ref containing a colon to be refused by the colon encoding")
+	}
+
+	if err := mirrorcat.SaveRedisMapping(client, indexed, mirrorcat.MirrorOptions{Depth: 1}, mirrorcat.RedisJSONEncoding); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"indexed mirror:dev:2", "legacy mirror:dev:1"}
+
+	got, err := collectMirrors(ctx, mirrorcat.RedisFinder{UniversalClient: client}, original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Logf("RedisFinder got: %v want: %v", got, want)
+		t.Fail()
+	}
+
+	index := mirrorcat.NewRedisIndex(client)
+	if err = index.Load(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err = collectMirrors(ctx, index, original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Logf("RedisIndex got: %v want: %v", got, want)
+		t.Fail()
+	}
+
+	options, found, err := mirrorcat.ReadRedisOptions(client, indexed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !found || options.Depth != 1 {
+		t.Logf("got: %+v (found: %v) want: depth of 1", options, found)
+		t.Fail()
+	}
+}
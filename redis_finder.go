@@ -3,14 +3,16 @@ package mirrorcat
 import (
 	"context"
 	"fmt"
-	"log"
 	"strings"
 
 	"github.com/go-redis/redis"
 )
 
-// RedisFinder implementes the MirrorFinder interface against a Redis Cache.
-type RedisFinder redis.Client
+// RedisFinder implementes the MirrorFinder interface against a Redis Cache. Any client may be used, so that
+// a single Redis server, a Sentinel-managed failover group, or a Redis Cluster can be consulted.
+type RedisFinder struct {
+	redis.UniversalClient
+}
 
 // RedisRemoteRef allows easy conversion to `string` from a `mirrorcat.RemoteRef`.
 type RedisRemoteRef RemoteRef
@@ -33,38 +35,110 @@ func (rrr RedisRemoteRef) String() string {
 	return fmt.Sprintf("%s:%s", rrr.Ref, rrr.Repository)
 }
 
+// RedisExclusionPrefix is prepended to the key of an original to find the Set of mirrors which should
+// NOT be pushed to, see `RedisFinder.FindExclusions`. It begins with "~", which no ref may contain, so that it can't be
+// mistaken for the key of an original whose ref is "exclude".
+const RedisExclusionPrefix = "~exclude:"
+
 // FindMirrors scrapes a Redis Cache, looking for any mirror entries.
 //
 // It is expected that the Redis Cache will contain a key which is the result of
 // `mirrorcat.RedisRemoteRef(original).String()`. At that key, MirrorCat expects to
 // find a Set of strings matching the format of the key, but targeting other repositories
-// and refs.
+// and refs. Mirrors stored using the version 2 schema, see `RedisSchemaVersion`, or using RedisJSONEncoding,
+// are also found.
 func (rf RedisFinder) FindMirrors(ctx context.Context, original RemoteRef, results chan<- RemoteRef) error {
-	defer close(results)
+	legacy, err := rf.members(ctx, "", original)
+	if err != nil {
+		close(results)
+		return err
+	}
+
+	indexed, err := ReadRedisMappings(rf.UniversalClient, original)
+	if err != nil {
+		close(results)
+		return err
+	}
 
-	base := redis.Client(rf)
+	found := legacy
+	for _, m := range indexed {
+		found = unionOf(found, []RemoteRef{m.Mirror})
+	}
 
-	memberCmd := base.SMembers(RedisRemoteRef(original).String())
+	return publishRemoteRefs(ctx, found, results)
+}
 
-	mirrors, err := memberCmd.Result()
+// FindExclusions scrapes a Redis Cache, looking for mirrors that should be ignored even if another
+// MirrorFinder with equal or lower priority has found them.
+//
+// These are stored in the same format as the entries read by `FindMirrors`, but at a key which also
+// has the prefix `RedisExclusionPrefix`.
+func (rf RedisFinder) FindExclusions(ctx context.Context, original RemoteRef, results chan<- RemoteRef) error {
+	found, err := rf.members(ctx, RedisExclusionPrefix, original)
 	if err != nil {
+		close(results)
 		return err
 	}
 
-	log.Printf("Found %d Redis entries for mirror %q", len(mirrors), RedisRemoteRef(original).String())
+	return publishRemoteRefs(ctx, found, results)
+}
+
+// FindOptions fetches the options stored alongside a mapping using the version 2 schema. Mappings stored
+// in the original format always have the zero value of MirrorOptions.
+func (rf RedisFinder) FindOptions(m Mapping) (MirrorOptions, error) {
+	options, _, err := ReadRedisOptions(rf.UniversalClient, m)
+	return options, err
+}
 
-	for _, item := range mirrors {
-		parsed, err := ParseRedisRemoteRef(item)
+// ListMirrors publishes every mapping stored in Redis, in either schema, ordered by original. Keys are found with
+// SCAN, so that Redis is not blocked while a large number of mappings are listed.
+func (rf RedisFinder) ListMirrors(ctx context.Context, results chan<- Mapping) error {
+	index, err := rf.load(ctx)
+	if err != nil {
+		close(results)
+		return err
+	}
+	return index.ListMirrors(ctx, results)
+}
+
+// ListExclusions publishes every exclusion stored in Redis, ordered by original.
+func (rf RedisFinder) ListExclusions(ctx context.Context, results chan<- Mapping) error {
+	index, err := rf.load(ctx)
+	if err != nil {
+		close(results)
+		return err
+	}
+	return index.ListExclusions(ctx, results)
+}
+
+// load reads every mapping currently stored in Redis into a RedisIndex, which is not kept up to date afterwards.
+func (rf RedisFinder) load(ctx context.Context) (*RedisIndex, error) {
+	index := NewRedisIndex(rf.UniversalClient)
+	if err := index.Load(ctx); err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+// members reads the Set at `prefix` followed by `original`, with every RedisRefEncoding.
+func (rf RedisFinder) members(ctx context.Context, prefix string, original RemoteRef) (found []RemoteRef, err error) {
+	for _, enc := range redisRefEncodings {
+		key := prefix + EncodeRedisRemoteRef(original, enc)
+		memberCmd := rf.SMembers(key)
+
+		var mirrors []string
+		mirrors, err = memberCmd.Result()
 		if err != nil {
-			return err
+			return
 		}
-		select {
-		case results <- RemoteRef(parsed):
-			// Intentionally Left Blank
-		case <-ctx.Done():
-			return ctx.Err()
+
+		if len(mirrors) > 0 || enc == RedisColonEncoding {
+			LoggerFrom(ctx).WithField("key", key).Debugf("Found %d Redis entries", len(mirrors))
 		}
+
+		parsed, _ := decodeRedisMembers(LoggerFrom(ctx), key, mirrors)
+		found = unionOf(found, parsed)
 	}
 
-	return nil
+	return
 }
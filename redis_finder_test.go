@@ -131,7 +131,7 @@ func TestRedisFinder_FindMirrors(t *testing.T) {
 		t.SkipNow()
 	}
 
-	subject := mirrorcat.RedisFinder(*client)
+	subject := mirrorcat.RedisFinder{UniversalClient: client}
 
 	testRepo, err := mirrorcat.ParseRedisRemoteRef(testKey)
 	if err != nil {
@@ -175,3 +175,86 @@ loop:
 		t.Fail()
 	}
 }
+
+func TestRedisFinder_ListMirrors(t *testing.T) {
+	viper.BindEnv("redis-connection", "MIRRORCAT_REDIS_CONNECTION")
+	viper.SetDefault("redis-connection", "redis://localhost:6379")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	connectionOptions, err := redis.ParseURL(viper.GetString("redis-connection"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := redis.NewClient(connectionOptions)
+	defer client.Close()
+
+	const legacyKey = "master:listRepo"
+	indexed := mirrorcat.Mapping{
+		Original: mirrorcat.RemoteRef{Repository: "listRepo", Ref: "dev"},
+		Mirror:   mirrorcat.RemoteRef{Repository: "otherRepo", Ref: "dev"},
+	}
+
+	if err = client.SAdd(legacyKey, "dev:listRepo").Err(); err != nil {
+		t.Log("Unable to connect to Redis instance: ", err)
+		t.SkipNow()
+	}
+	defer client.Del(legacyKey)
+
+	if err = mirrorcat.SaveRedisMapping(client, indexed, mirrorcat.MirrorOptions{}, mirrorcat.RedisColonEncoding); err != nil {
+		t.Fatal(err)
+	}
+	defer mirrorcat.DeleteRedisMapping(client, indexed)
+
+	listed, err := mirrorcat.CollectMappings(ctx, mirrorcat.RedisFinder{UniversalClient: client})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[mirrorcat.Mapping]struct{}{
+		indexed: {},
+		{
+			Original: mirrorcat.RemoteRef{Repository: "listRepo", Ref: "master"},
+			Mirror:   mirrorcat.RemoteRef{Repository: "listRepo", Ref: "dev"},
+		}: {},
+	}
+	for _, m := range listed {
+		delete(want, m)
+	}
+	for unseen := range want {
+		t.Log("didn't see expected mapping: ", unseen)
+		t.Fail()
+	}
+}
+
+func TestRedisFinder_FindMirrors_refNamedExclude(t *testing.T) {
+	client := connectTestRedis(t)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// Before exclusions were given a prefix that no ref may contain, this key was read as an exclusion.
+	const testKey = "exclude:https://example.com/excludeRepo.git"
+	original := mirrorcat.RemoteRef{Repository: "https://example.com/excludeRepo.git", Ref: "exclude"}
+
+	if err := client.SAdd(testKey, "dev:https://example.com/excludeMirror.git", "not a remote ref").Err(); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Del(testKey)
+
+	subject := mirrorcat.RedisFinder{UniversalClient: client}
+
+	got, err := collectMirrors(ctx, subject, original)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"https://example.com/excludeMirror.git:dev"}
+	if len(got) != len(want) || got[0] != want[0] {
+		t.Logf("got: %v want: %v", got, want)
+		t.Fail()
+	}
+}
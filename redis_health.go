@@ -0,0 +1,134 @@
+package mirrorcat
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// RedisHealth is a snapshot of what a RedisMonitor last learned about a Redis deployment.
+type RedisHealth struct {
+	Healthy             bool         `json:"healthy"`
+	Since               time.Time    `json:"since"`
+	LastChecked         time.Time    `json:"lastChecked"`
+	LastError           string       `json:"lastError,omitempty"`
+	ConsecutiveFailures int          `json:"consecutiveFailures"`
+	Circuit             BreakerState `json:"circuit"`
+}
+
+// RedisMonitor sends a PING to Redis at a regular interval, to learn whether it can be reached. While Redis is
+// failing, it is checked more often, backing off exponentially, so that MirrorCat reconnects soon after Redis
+// recovers.
+//
+// If `Breaker` is set, it is told the outcome of each check. It is opened after enough checks fail, and closed as
+// soon as one succeeds.
+type RedisMonitor struct {
+	Client   redis.UniversalClient
+	Breaker  *CircuitBreaker
+	Interval time.Duration
+	Backoff  Backoff
+
+	// OnHealthy is called each time Redis becomes reachable, including the first time that it is checked.
+	OnHealthy func()
+
+	// OnUnhealthy is called each time Redis stops being reachable, including the first time that it is checked.
+	OnUnhealthy func(error)
+
+	sync.RWMutex
+	health  RedisHealth
+	checked bool
+}
+
+// NewRedisMonitor creates a RedisMonitor which checks `client` every `interval` while it is healthy, and backs off
+// from one second to `interval` while it is not.
+func NewRedisMonitor(client redis.UniversalClient, breaker *CircuitBreaker, interval time.Duration) *RedisMonitor {
+	minBackoff := time.Second
+	if interval < minBackoff {
+		minBackoff = interval
+	}
+
+	return &RedisMonitor{
+		Client:   client,
+		Breaker:  breaker,
+		Interval: interval,
+		Backoff: Backoff{
+			Min: minBackoff,
+			Max: interval,
+		},
+	}
+}
+
+// Run checks Redis repeatedly, until `ctx` is cancelled.
+func (rm *RedisMonitor) Run(ctx context.Context) error {
+	for {
+		delay := rm.Interval
+		if rm.Check() != nil {
+			delay = rm.Backoff.Next()
+		} else {
+			rm.Backoff.Reset()
+		}
+
+		select {
+		case <-time.After(delay):
+			// Intentionally Left Blank
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Check sends a single PING to Redis, and records the outcome.
+func (rm *RedisMonitor) Check() error {
+	err := rm.Client.Ping().Err()
+
+	if rm.Breaker != nil {
+		if err == nil {
+			rm.Breaker.Succeeded()
+		} else {
+			rm.Breaker.Failed()
+		}
+	}
+
+	rm.Lock()
+	now := time.Now()
+	changed := !rm.checked || rm.health.Healthy != (err == nil)
+	rm.checked = true
+	rm.health.LastChecked = now
+	rm.health.Healthy = err == nil
+	if changed {
+		rm.health.Since = now
+	}
+	if err == nil {
+		rm.health.LastError = ""
+		rm.health.ConsecutiveFailures = 0
+	} else {
+		rm.health.LastError = err.Error()
+		rm.health.ConsecutiveFailures++
+	}
+	rm.Unlock()
+
+	if !changed {
+		return err
+	}
+
+	if err == nil && rm.OnHealthy != nil {
+		rm.OnHealthy()
+	} else if err != nil && rm.OnUnhealthy != nil {
+		rm.OnUnhealthy(err)
+	}
+	return err
+}
+
+// Health fetches the outcome of the most recent check. Before Redis has been checked, it is reported as unhealthy.
+func (rm *RedisMonitor) Health() RedisHealth {
+	rm.RLock()
+	health := rm.health
+	rm.RUnlock()
+
+	if rm.Breaker != nil {
+		health.Circuit = rm.Breaker.State()
+	}
+	return health
+}
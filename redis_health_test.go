@@ -0,0 +1,73 @@
+package mirrorcat_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Azure/mirrorcat"
+	"github.com/go-redis/redis"
+)
+
+func TestRedisMonitor_Check(t *testing.T) {
+	client := connectTestRedis(t)
+
+	breaker := mirrorcat.NewCircuitBreaker(1, time.Hour)
+	breaker.Trip()
+
+	healthy := 0
+	subject := mirrorcat.NewRedisMonitor(client, breaker, time.Minute)
+	subject.OnHealthy = func() { healthy++ }
+
+	if err := subject.Check(); err != nil {
+		t.Fatal(err)
+	}
+	if err := subject.Check(); err != nil {
+		t.Fatal(err)
+	}
+
+	health := subject.Health()
+	if !health.Healthy || health.Circuit != mirrorcat.BreakerClosed {
+		t.Logf("got: %+v want: healthy with a closed circuit", health)
+		t.Fail()
+	}
+	if healthy != 1 {
+		t.Logf("got: %d calls to OnHealthy want: 1", healthy)
+		t.Fail()
+	}
+}
+
+func TestRedisMonitor_Check_Unreachable(t *testing.T) {
+	// Nothing is expected to be listening on port 1, so this client should never be able to connect.
+	client := redis.NewClient(&redis.Options{
+		Addr:        "127.0.0.1:1",
+		DialTimeout: time.Second,
+		MaxRetries:  0,
+	})
+	defer client.Close()
+
+	breaker := mirrorcat.NewCircuitBreaker(2, time.Hour)
+
+	var reported error
+	subject := mirrorcat.NewRedisMonitor(client, breaker, time.Minute)
+	subject.OnUnhealthy = func(err error) { reported = err }
+
+	for i := 0; i < 2; i++ {
+		if err := subject.Check(); err == nil {
+			t.Fatal("expected an error while connecting to an unused port")
+		}
+	}
+
+	if reported == nil {
+		t.Error("expected OnUnhealthy to be called")
+	}
+
+	health := subject.Health()
+	if health.Healthy || health.ConsecutiveFailures != 2 || health.LastError == "" {
+		t.Logf("got: %+v want: unhealthy after 2 failures", health)
+		t.Fail()
+	}
+	if health.Circuit != mirrorcat.BreakerOpen {
+		t.Logf("got: %v want: %v", health.Circuit, mirrorcat.BreakerOpen)
+		t.Fail()
+	}
+}
@@ -0,0 +1,522 @@
+package mirrorcat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/sirupsen/logrus"
+)
+
+// RedisNotificationChannel is a pub/sub channel that RedisIndex listens to in addition to keyspace notifications.
+// Tools which modify mappings in a Redis instance that does not have keyspace notifications enabled may publish
+// the key that they modified to this channel.
+const RedisNotificationChannel = "mirrorcat:changes"
+
+// RedisChange describes a modification to the mirrors, or exclusions, of an original that was observed in Redis.
+type RedisChange struct {
+	Original    RemoteRef   `json:"original"`
+	Exclusion   bool        `json:"exclusion"`
+	NewOriginal bool        `json:"newOriginal"`
+	Added       []RemoteRef `json:"added"`
+	Removed     []RemoteRef `json:"removed"`
+
+	// Key is the Redis key which was reported to have changed. Event is the keyspace event that reported it, like
+	// "sadd" or "expired", and is empty when the key was published to `RedisNotificationChannel` instead.
+	Key   string `json:"key"`
+	Event string `json:"event,omitempty"`
+}
+
+// AuditRecords describes each mirror, or exclusion, that was added or removed as a separate AuditRecord. Mirrors
+// which were removed because their mapping expired are recorded as MappingExpired.
+func (change RedisChange) AuditRecords(at time.Time) []AuditRecord {
+	removal := MappingRemoved
+	if change.Event == "expired" {
+		removal = MappingExpired
+	}
+
+	records := make([]AuditRecord, 0, len(change.Added)+len(change.Removed))
+	describe := func(mirror RemoteRef, kind MappingChange) {
+		records = append(records, AuditRecord{
+			Time:      at,
+			Original:  change.Original,
+			Mirror:    mirror,
+			Change:    kind,
+			Key:       change.Key,
+			Exclusion: change.Exclusion,
+			Outcome:   JobSucceeded,
+		})
+	}
+
+	for _, mirror := range change.Added {
+		describe(mirror, MappingAdded)
+	}
+	for _, mirror := range change.Removed {
+		describe(mirror, removal)
+	}
+	return records
+}
+
+// RedisIndex keeps an in-memory copy of the mappings stored in a Redis Cache, in the format read by RedisFinder.
+// Once `Watch` has been called, it listens for keyspace notifications to keep the copy up-to-date, so that
+// finding mirrors does not require a round trip to Redis.
+type RedisIndex struct {
+	sync.RWMutex
+	client redis.UniversalClient
+	sets   map[redisSet][]RemoteRef
+
+	// OnChange, if not nil, is called each time that `Watch` notices a set of mirrors or exclusions change.
+	OnChange func(RedisChange)
+
+	// OnLoad, if not nil, is called each time that `Watch` finishes reading every mapping from Redis.
+	OnLoad func()
+}
+
+// NewRedisIndex creates an empty RedisIndex, which will read from `client` once it is loaded or watched.
+func NewRedisIndex(client redis.UniversalClient) *RedisIndex {
+	return &RedisIndex{
+		client: client,
+		sets:   make(map[redisSet][]RemoteRef),
+	}
+}
+
+// redisSet identifies one of the keys in Redis which may hold mirrors or exclusions of an original.
+type redisSet struct {
+	original RemoteRef
+	kind     redisKeyKind
+	enc      RedisRefEncoding
+}
+
+// mirrorKinds and exclusionKinds group together the kinds of keys which hold the same sort of information.
+var (
+	mirrorKinds    = []redisKeyKind{redisLegacyMirrors, redisIndexedMirrors}
+	exclusionKinds = []redisKeyKind{redisLegacyExclusions}
+)
+
+// membersOf combines every member of each set of `original` which has one of `kinds`, in any encoding. The caller
+// must hold at least a read lock.
+func (ri *RedisIndex) membersOf(original RemoteRef, kinds []redisKeyKind) (members []RemoteRef) {
+	for _, kind := range kinds {
+		for _, enc := range redisRefEncodings {
+			members = unionOf(members, ri.sets[redisSet{original: original, kind: kind, enc: enc}])
+		}
+	}
+	return
+}
+
+// EnableRedisKeyspaceNotifications configures a Redis instance to publish the keyspace notifications that a RedisIndex
+// relies on, while preserving any other notifications that were already enabled.
+//
+// Many hosted Redis offerings do not allow the CONFIG command, in which case notifications must be enabled by
+// other means.
+func EnableRedisKeyspaceNotifications(client redis.UniversalClient) error {
+	const required = "Kghsx"
+
+	if cluster, ok := client.(*redis.ClusterClient); ok {
+		return cluster.ForEachMaster(func(master *redis.Client) error {
+			return EnableRedisKeyspaceNotifications(master)
+		})
+	}
+
+	current, err := client.ConfigGet("notify-keyspace-events").Result()
+	if err != nil {
+		return err
+	}
+
+	var flags string
+	if len(current) == 2 {
+		flags, _ = current[1].(string)
+	}
+
+	for _, flag := range required {
+		if !strings.ContainsRune(flags, flag) {
+			flags += string(flag)
+		}
+	}
+
+	return client.ConfigSet("notify-keyspace-events", flags).Err()
+}
+
+// Load reads every mapping from Redis, replacing the contents of the index.
+func (ri *RedisIndex) Load(ctx context.Context) error {
+	loaded := make(map[redisSet][]RemoteRef)
+
+	err := scanRedisMappingKeys(ctx, ri.client, func(key string, original RemoteRef, kind redisKeyKind, enc RedisRefEncoding) error {
+		if kind == redisIndexedMapping {
+			return nil
+		}
+
+		members, err := readRedisKey(ri.client, key, original, kind, enc)
+		if err != nil || len(members) == 0 {
+			return err
+		}
+
+		loaded[redisSet{original: original, kind: kind, enc: enc}] = members
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	ri.Lock()
+	defer ri.Unlock()
+	ri.sets = loaded
+	return nil
+}
+
+// Watch subscribes to keyspace notifications, and to `RedisNotificationChannel`, then loads every mapping. Afterwards,
+// each time a mapping key is reported to have changed it is re-read, until `ctx` is cancelled.
+//
+// Redis Cluster only publishes keyspace notifications from the node that holds a key, and only one node is
+// subscribed to, so in that case changes should also be published to `RedisNotificationChannel`.
+func (ri *RedisIndex) Watch(ctx context.Context) error {
+	var db int
+	if single, ok := ri.client.(*redis.Client); ok {
+		db = single.Options().DB
+	}
+	prefix := fmt.Sprintf("__keyspace@%d__:", db)
+
+	pubsub := ri.client.PSubscribe(prefix + "*")
+	defer pubsub.Close()
+
+	if err := pubsub.Subscribe(RedisNotificationChannel); err != nil {
+		return err
+	}
+
+	// Waiting for both subscriptions to be confirmed ensures that no change made after loading will be missed.
+	for confirmed := 0; confirmed < 2; {
+		msg, err := pubsub.Receive()
+		if err != nil {
+			return err
+		}
+		if _, ok := msg.(*redis.Subscription); ok {
+			confirmed++
+		}
+	}
+
+	if err := ri.Load(ctx); err != nil {
+		return err
+	}
+	if ri.OnLoad != nil {
+		ri.OnLoad()
+	}
+
+	messages := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg, ok := <-messages:
+			if !ok {
+				return errors.New("redis subscription was closed")
+			}
+
+			key, event := msg.Payload, ""
+			if msg.Channel != RedisNotificationChannel {
+				key, event = strings.TrimPrefix(msg.Channel, prefix), msg.Payload
+			}
+
+			if err := ri.refresh(key, event); err != nil {
+				Logger.WithError(err).WithField("key", key).Warn("Unable to refresh Redis key")
+			}
+		}
+	}
+}
+
+// refresh re-reads a single key from Redis, and updates the index to match it. `event` is reported in any RedisChange
+// that results.
+func (ri *RedisIndex) refresh(key, event string) error {
+	original, kind, enc, ok := parseRedisKey(key)
+	if !ok {
+		return nil
+	}
+
+	// A mapping's Hash changing, most importantly by expiring, may change which mirrors its original has.
+	read := key
+	if kind == redisIndexedMapping {
+		read, kind = RedisIndexKey(original, enc), redisIndexedMirrors
+	}
+
+	members, err := readRedisKey(ri.client, read, original, kind, enc)
+	if err != nil {
+		return err
+	}
+
+	kinds := mirrorKinds
+	if kind == redisLegacyExclusions {
+		kinds = exclusionKinds
+	}
+
+	target := redisSet{original: original, kind: kind, enc: enc}
+
+	ri.Lock()
+	before := ri.membersOf(original, kinds)
+	if len(members) == 0 {
+		delete(ri.sets, target)
+	} else {
+		ri.sets[target] = members
+	}
+	after := ri.membersOf(original, kinds)
+	ri.Unlock()
+
+	change := RedisChange{
+		Original:    original,
+		Exclusion:   kind == redisLegacyExclusions,
+		NewOriginal: len(before) == 0,
+		Added:       differenceOf(after, before),
+		Removed:     differenceOf(before, after),
+		Key:         key,
+		Event:       event,
+	}
+
+	if ri.OnChange != nil && (len(change.Added) > 0 || len(change.Removed) > 0) {
+		ri.OnChange(change)
+	}
+	return nil
+}
+
+// FindMirrors publishes the mirrors of `original` that were most recently read from Redis, in either schema.
+func (ri *RedisIndex) FindMirrors(ctx context.Context, original RemoteRef, results chan<- RemoteRef) error {
+	ri.RLock()
+	found := ri.membersOf(original, mirrorKinds)
+	ri.RUnlock()
+
+	return publishRemoteRefs(ctx, found, results)
+}
+
+// FindExclusions publishes the exclusions of `original` that were most recently read from Redis.
+func (ri *RedisIndex) FindExclusions(ctx context.Context, original RemoteRef, results chan<- RemoteRef) error {
+	ri.RLock()
+	found := ri.membersOf(original, exclusionKinds)
+	ri.RUnlock()
+
+	return publishRemoteRefs(ctx, found, results)
+}
+
+// FindOptions fetches the options stored alongside a mapping using the version 2 schema. Options are not
+// kept in memory, so this always makes a round trip to Redis.
+func (ri *RedisIndex) FindOptions(m Mapping) (MirrorOptions, error) {
+	options, _, err := ReadRedisOptions(ri.client, m)
+	return options, err
+}
+
+// publishRemoteRefs sends each of `found` to `results`, then closes it.
+func publishRemoteRefs(ctx context.Context, found []RemoteRef, results chan<- RemoteRef) error {
+	defer close(results)
+
+	for _, m := range found {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case results <- m:
+			// Intentionally Left Blank
+		}
+	}
+	return nil
+}
+
+// ListMirrors publishes every mapping that was most recently read from Redis, ordered by original.
+func (ri *RedisIndex) ListMirrors(ctx context.Context, results chan<- Mapping) error {
+	return publishMappings(ctx, ri.mappingsOf(mirrorKinds), results)
+}
+
+// ListExclusions publishes every exclusion that was most recently read from Redis, ordered by original.
+func (ri *RedisIndex) ListExclusions(ctx context.Context, results chan<- Mapping) error {
+	return publishMappings(ctx, ri.mappingsOf(exclusionKinds), results)
+}
+
+// mappingsOf finds every mapping held by a set which has one of `kinds`.
+func (ri *RedisIndex) mappingsOf(kinds []redisKeyKind) (mappings []Mapping) {
+	ri.RLock()
+	defer ri.RUnlock()
+
+	originals := make(map[RemoteRef]struct{})
+	for set := range ri.sets {
+		for _, kind := range kinds {
+			if set.kind == kind {
+				originals[set.original] = struct{}{}
+			}
+		}
+	}
+
+	for original := range originals {
+		for _, m := range ri.membersOf(original, kinds) {
+			mappings = append(mappings, Mapping{Original: original, Mirror: m})
+		}
+	}
+	return
+}
+
+// publishMappings sorts `mappings`, sends each of them to `results`, then closes it.
+func publishMappings(ctx context.Context, mappings []Mapping, results chan<- Mapping) error {
+	defer close(results)
+
+	sortMappings(mappings)
+
+	for _, m := range mappings {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case results <- m:
+			// Intentionally Left Blank
+		}
+	}
+	return nil
+}
+
+// redisKeyKind distinguishes between the different roles that a key in Redis may play for MirrorCat.
+type redisKeyKind int
+
+const (
+	redisLegacyMirrors redisKeyKind = iota
+	redisLegacyExclusions
+	redisIndexedMirrors
+	redisIndexedMapping
+)
+
+// parseRedisKey determines which original, if any, a Redis key holds the mirrors or exclusions of, in which format,
+// and with which encoding.
+func parseRedisKey(key string) (original RemoteRef, kind redisKeyKind, enc RedisRefEncoding, ok bool) {
+	switch {
+	case key == RedisSchemaKey:
+		return
+	case strings.HasPrefix(key, redisMappingPrefix):
+		var m Mapping
+		m, enc, ok = parseRedisMappingHashKey(key)
+		return m.Original, redisIndexedMapping, enc, ok
+	case strings.HasPrefix(key, redisIndexPrefix):
+		key = strings.TrimPrefix(key, redisIndexPrefix)
+		kind = redisIndexedMirrors
+	case strings.HasPrefix(key, RedisExclusionPrefix):
+		key = strings.TrimPrefix(key, RedisExclusionPrefix)
+		kind = redisLegacyExclusions
+	default:
+		kind = redisLegacyMirrors
+	}
+
+	original, enc, err := DecodeRedisRemoteRef(key)
+	if err != nil {
+		return
+	}
+	return original, kind, enc, true
+}
+
+// readRedisKey reads the mirrors or exclusions held by a key, according to its kind.
+func readRedisKey(client redis.UniversalClient, key string, original RemoteRef, kind redisKeyKind, enc RedisRefEncoding) ([]RemoteRef, error) {
+	if kind != redisIndexedMirrors {
+		members, _, err := readRedisMembers(client, key)
+		return members, err
+	}
+
+	mappings, err := readRedisIndex(client, original, enc)
+	if err != nil {
+		return nil, err
+	}
+
+	members := make([]RemoteRef, 0, len(mappings))
+	for _, m := range mappings {
+		members = append(members, m.Mirror)
+	}
+	sortRemoteRefs(members)
+	return members, nil
+}
+
+// scanRedisMappingKeys uses SCAN, rather than the blocking KEYS command, to visit each key which may hold mirrors or exclusions.
+// In a Redis Cluster, each master is scanned in turn.
+func scanRedisMappingKeys(ctx context.Context, client redis.UniversalClient, visit func(key string, original RemoteRef, kind redisKeyKind, enc RedisRefEncoding) error) error {
+	if cluster, ok := client.(*redis.ClusterClient); ok {
+		var visiting sync.Mutex
+		return cluster.ForEachMaster(func(master *redis.Client) error {
+			return scanRedisMappingKeys(ctx, master, func(key string, original RemoteRef, kind redisKeyKind, enc RedisRefEncoding) error {
+				visiting.Lock()
+				defer visiting.Unlock()
+				return visit(key, original, kind, enc)
+			})
+		})
+	}
+
+	const batchSize = 100
+
+	var cursor uint64
+	for {
+		keys, next, err := client.Scan(cursor, "*", batchSize).Result()
+		if err != nil {
+			return err
+		}
+
+		for _, key := range keys {
+			if err = ctx.Err(); err != nil {
+				return err
+			}
+
+			original, kind, enc, ok := parseRedisKey(key)
+			if !ok {
+				continue
+			}
+
+			if err = visit(key, original, kind, enc); err != nil {
+				return err
+			}
+		}
+
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+// readRedisMembers reads the RemoteRefs stored in a Redis Set. Keys which do not hold a Set are skipped, as are
+// members which cannot be parsed, see `decodeRedisMembers`.
+func readRedisMembers(client redis.UniversalClient, key string) (members []RemoteRef, skipped int, err error) {
+	raw, err := client.SMembers(key).Result()
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "WRONGTYPE") {
+			err = nil
+		}
+		return
+	}
+
+	members, skipped = decodeRedisMembers(Logger, key, raw)
+	return
+}
+
+// decodeRedisMembers parses the members of the Set at `key`. Members which cannot be parsed are logged and
+// skipped, so that one malformed entry doesn't hide every other mirror or exclusion of an original.
+func decodeRedisMembers(logger *logrus.Entry, key string, raw []string) (members []RemoteRef, skipped int) {
+	for _, item := range raw {
+		parsed, _, err := DecodeRedisRemoteRef(item)
+		if err != nil {
+			logger.WithError(err).WithField("key", key).Warn("Skipping member of Redis key")
+			skipped++
+			continue
+		}
+		members = append(members, parsed)
+	}
+	sortRemoteRefs(members)
+	return
+}
+
+// unionOf combines two lists of RemoteRefs, so that each RemoteRef appears once. Those in `a` come first.
+func unionOf(a, b []RemoteRef) []RemoteRef {
+	return append(append([]RemoteRef(nil), a...), differenceOf(b, a)...)
+}
+
+// differenceOf finds each RemoteRef in `a` that is not in `b`.
+func differenceOf(a, b []RemoteRef) (diff []RemoteRef) {
+	present := make(map[RemoteRef]struct{}, len(b))
+	for _, item := range b {
+		present[item] = struct{}{}
+	}
+
+	for _, item := range a {
+		if _, ok := present[item]; !ok {
+			diff = append(diff, item)
+		}
+	}
+	return
+}
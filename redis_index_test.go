@@ -0,0 +1,138 @@
+package mirrorcat_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Azure/mirrorcat"
+)
+
+func TestRedisIndex_Watch(t *testing.T) {
+	client := connectTestRedis(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	const testKey = "master:indexedRepo"
+	original := mirrorcat.RemoteRef{Repository: "indexedRepo", Ref: "master"}
+	added := mirrorcat.RemoteRef{Repository: "indexedMirror", Ref: "dev"}
+	defer client.Del(testKey)
+
+	loaded := make(chan struct{}, 1)
+	changes := make(chan mirrorcat.RedisChange, 1)
+
+	subject := mirrorcat.NewRedisIndex(client)
+	subject.OnLoad = func() { loaded <- struct{}{} }
+	subject.OnChange = func(change mirrorcat.RedisChange) { changes <- change }
+
+	watchErrs := make(chan error, 1)
+	go func() {
+		watchErrs <- subject.Watch(ctx)
+	}()
+
+	select {
+	case <-loaded:
+		// Intentionally Left Blank
+	case err := <-watchErrs:
+		t.Fatal(err)
+	}
+
+	if err := client.SAdd(testKey, mirrorcat.RedisRemoteRef(added).String()).Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Publishing the key explicitly allows this test to pass even when keyspace notifications are disabled.
+	if err := client.Publish(mirrorcat.RedisNotificationChannel, testKey).Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case change := <-changes:
+		if change.Original != original {
+			t.Logf("got: %v want: %v", change.Original, original)
+			t.Fail()
+		}
+		if !change.NewOriginal {
+			t.Log("expected the original to be reported as new")
+			t.Fail()
+		}
+		if len(change.Added) != 1 || change.Added[0] != added {
+			t.Logf("got: %v want: %v", change.Added, []mirrorcat.RemoteRef{added})
+			t.Fail()
+		}
+		if change.Key != testKey {
+			t.Logf("got: %q want: %q", change.Key, testKey)
+			t.Fail()
+		}
+	case <-ctx.Done():
+		t.Fatal(ctx.Err())
+	}
+
+	got, err := collectMirrors(ctx, subject, original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0] != "indexedMirror:dev" {
+		t.Logf("got: %v want: %v", got, []string{"indexedMirror:dev"})
+		t.Fail()
+	}
+}
+
+func TestRedisChange_AuditRecords(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mirrorcat_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	sink, err := mirrorcat.NewFileAuditSink(dir, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	original := mirrorcat.RemoteRef{Repository: "https://github.com/Azure/mirrorcat.git", Ref: "master"}
+	added := mirrorcat.RemoteRef{Repository: "https://github.com/marstr/mirrorcat.git", Ref: "master"}
+	expired := mirrorcat.RemoteRef{Repository: "https://github.com/haydenmc/mirrorcat.git", Ref: "dev"}
+
+	change := mirrorcat.RedisChange{
+		Original: original,
+		Added:    []mirrorcat.RemoteRef{added},
+		Removed:  []mirrorcat.RemoteRef{expired},
+		Key:      "mirrorcat:v2:mapping:master:https://github.com/Azure/mirrorcat.git dev:https://github.com/haydenmc/mirrorcat.git",
+		Event:    "expired",
+	}
+
+	ctx := context.Background()
+	started := time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+	for _, record := range change.AuditRecords(started) {
+		if err = sink.Audit(ctx, record); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := sink.ReadAudit(ctx, mirrorcat.AuditQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("got: %d records want: 2", len(got))
+	}
+
+	want := map[mirrorcat.RemoteRef]mirrorcat.MappingChange{
+		added:   mirrorcat.MappingAdded,
+		expired: mirrorcat.MappingExpired,
+	}
+	for _, record := range got {
+		if record.Change != want[record.Mirror] {
+			t.Logf("got: %q want: %q for %v", record.Change, want[record.Mirror], record.Mirror)
+			t.Fail()
+		}
+		if record.Original != original || record.Key != change.Key || !record.Time.Equal(started) {
+			t.Logf("got: %+v want: a record of %v from %q at %v", record, original, change.Key, started)
+			t.Fail()
+		}
+	}
+}
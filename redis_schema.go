@@ -0,0 +1,296 @@
+package mirrorcat
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// RedisSchemaVersion is the newest layout of mappings in Redis that MirrorCat knows how to read and write.
+//
+// Version 1 is the original layout, where the key `mirrorcat.RedisRemoteRef(original).String()` holds a Set of
+// mirrors formatted in the same way. It carries no information beyond which mirrors exist, and is still read.
+//
+// In either version, any key or member may instead be written using RedisJSONEncoding.
+//
+// Version 2 stores each mapping in its own Hash, see `RedisMappingKey`, so that it may have MirrorOptions. Each
+// original also has an index Set, see `RedisIndexKey`, which lists the mirrors that have a Hash. A mapping with
+// an expiry is removed by Redis itself, by setting a TTL on its Hash.
+const RedisSchemaVersion = 2
+
+// RedisSchemaKey holds the newest RedisSchemaVersion that has been written to a Redis instance.
+const RedisSchemaKey = "mirrorcat:schema"
+
+const (
+	redisIndexPrefix   = "mirrorcat:v2:index:"
+	redisMappingPrefix = "mirrorcat:v2:mapping:"
+
+	// redisMappingSeparator splits the original from the mirror in the key of a mapping's Hash. Neither refs
+	// nor URLs may contain a space, and SaveRedisMapping refuses paths that do unless RedisJSONEncoding is used,
+	// so it is always safe to split on the first one that is not inside of a JSON string.
+	redisMappingSeparator = " "
+)
+
+// RedisMapping is a mapping read from a version 2 Redis schema, along with the options stored beside it.
+type RedisMapping struct {
+	Mapping
+	MirrorOptions
+}
+
+// RedisIndexKey finds the key of the Set which lists each mirror of `original` in the version 2 schema.
+func RedisIndexKey(original RemoteRef, enc RedisRefEncoding) string {
+	return redisIndexPrefix + EncodeRedisRemoteRef(original, enc)
+}
+
+// RedisMappingKey finds the key of the Hash which holds the options of a mapping in the version 2 schema.
+func RedisMappingKey(m Mapping, enc RedisRefEncoding) string {
+	return redisMappingPrefix + EncodeRedisRemoteRef(m.Original, enc) + redisMappingSeparator + EncodeRedisRemoteRef(m.Mirror, enc)
+}
+
+// SaveRedisMapping writes a mapping, and its options, using the version 2 schema. Any options previously stored
+// for the mapping are replaced. If `options.Expires` is set, Redis will forget the mapping at that time.
+//
+// Each key and member is written using `enc`. A copy of the mapping written using a different encoding is
+// left alone.
+func SaveRedisMapping(client redis.UniversalClient, m Mapping, options MirrorOptions, enc RedisRefEncoding) error {
+	for _, target := range []RemoteRef{m.Original, m.Mirror} {
+		if enc != RedisColonEncoding {
+			break
+		}
+		if strings.Contains(target.Repository, redisMappingSeparator) || strings.Contains(target.Ref, redisMappingSeparator) {
+			return fmt.Errorf("%q (%s) may not contain a space unless it is JSON encoded", target.Repository, target.Ref)
+		}
+		if strings.ContainsRune(target.Ref, ':') {
+			return fmt.Errorf("%q (%s) may not contain a colon unless it is JSON encoded", target.Repository, target.Ref)
+		}
+	}
+
+	if !options.Expires.IsZero() && !options.Expires.After(time.Now()) {
+		return fmt.Errorf("the mapping from %v to %v would have already expired at %v", m.Original, m.Mirror, options.Expires)
+	}
+
+	key := RedisMappingKey(m, enc)
+	_, err := client.TxPipelined(func(pipe redis.Pipeliner) error {
+		pipe.Del(key)
+		pipe.HMSet(key, encodeMirrorOptions(m, options, enc))
+		if !options.Expires.IsZero() {
+			pipe.ExpireAt(key, options.Expires)
+		}
+		pipe.SAdd(RedisIndexKey(m.Original, enc), EncodeRedisRemoteRef(m.Mirror, enc))
+		pipe.Set(RedisSchemaKey, RedisSchemaVersion, 0)
+		return nil
+	})
+	return err
+}
+
+// DeleteRedisMapping removes a mapping that was written using the version 2 schema, with any encoding.
+func DeleteRedisMapping(client redis.UniversalClient, m Mapping) error {
+	_, err := client.TxPipelined(func(pipe redis.Pipeliner) error {
+		for _, enc := range redisRefEncodings {
+			pipe.Del(RedisMappingKey(m, enc))
+			pipe.SRem(RedisIndexKey(m.Original, enc), EncodeRedisRemoteRef(m.Mirror, enc))
+		}
+		return nil
+	})
+	return err
+}
+
+// ReadRedisMappings reads each mapping of `original` that was written using the version 2 schema, with any encoding,
+// ordered by mirror.
+//
+// Mirrors that are still in the index, but whose Hash has expired, are removed from the index as they are found.
+func ReadRedisMappings(client redis.UniversalClient, original RemoteRef) (mappings []RedisMapping, err error) {
+	seen := make(map[RemoteRef]struct{})
+
+	for _, enc := range redisRefEncodings {
+		var found []RedisMapping
+		if found, err = readRedisIndex(client, original, enc); err != nil {
+			return
+		}
+
+		for _, m := range found {
+			if _, ok := seen[m.Mirror]; ok {
+				continue
+			}
+			seen[m.Mirror] = struct{}{}
+			mappings = append(mappings, m)
+		}
+	}
+
+	sort.Slice(mappings, func(i, j int) bool {
+		return lessRemoteRef(mappings[i].Mirror, mappings[j].Mirror)
+	})
+	return
+}
+
+// readRedisIndex reads each mapping listed by the version 2 index of `original` that was written using `enc`.
+func readRedisIndex(client redis.UniversalClient, original RemoteRef, enc RedisRefEncoding) (mappings []RedisMapping, err error) {
+	indexKey := RedisIndexKey(original, enc)
+
+	members, err := client.SMembers(indexKey).Result()
+	if err != nil {
+		return
+	}
+
+	for _, member := range members {
+		mirror, _, err := DecodeRedisRemoteRef(member)
+		if err != nil {
+			return nil, err
+		}
+
+		m := Mapping{Original: original, Mirror: mirror}
+		options, found, err := readRedisOptions(client, m, enc)
+		if err != nil {
+			return nil, err
+		}
+
+		if !found {
+			if err = client.SRem(indexKey, member).Err(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		mappings = append(mappings, RedisMapping{Mapping: m, MirrorOptions: options})
+	}
+	return
+}
+
+// ReadRedisOptions fetches the options of a mapping that was written using the version 2 schema, with any encoding.
+// If there is no such mapping, `found` is false.
+func ReadRedisOptions(client redis.UniversalClient, m Mapping) (options MirrorOptions, found bool, err error) {
+	for _, enc := range redisRefEncodings {
+		if options, found, err = readRedisOptions(client, m, enc); found || err != nil {
+			return
+		}
+	}
+	return
+}
+
+func readRedisOptions(client redis.UniversalClient, m Mapping, enc RedisRefEncoding) (options MirrorOptions, found bool, err error) {
+	fields, err := client.HGetAll(RedisMappingKey(m, enc)).Result()
+	if err != nil || len(fields) == 0 {
+		return
+	}
+
+	options, err = decodeMirrorOptions(fields)
+	found = err == nil
+	return
+}
+
+// MigrateRedisMappings copies every mapping stored using the version 1 schema into the version 2 schema, keeping
+// the encoding of the key it was found at. Unless `keepLegacy` is set, each version 1 Set is deleted once all of its
+// members have been copied. A Set with members that could not be parsed is kept, so that they can be repaired by hand.
+//
+// Exclusions do not have options, so they continue to be stored as described by `RedisFinder.FindExclusions`.
+func MigrateRedisMappings(ctx context.Context, client redis.UniversalClient, keepLegacy bool) (migrated []Mapping, err error) {
+	err = scanRedisMappingKeys(ctx, client, func(key string, original RemoteRef, kind redisKeyKind, enc RedisRefEncoding) error {
+		if kind != redisLegacyMirrors {
+			return nil
+		}
+
+		members, skipped, err := readRedisMembers(client, key)
+		if err != nil {
+			return err
+		}
+
+		for _, mirror := range members {
+			m := Mapping{Original: original, Mirror: mirror}
+			if _, found, err := ReadRedisOptions(client, m); err != nil {
+				return err
+			} else if !found {
+				if err = SaveRedisMapping(client, m, MirrorOptions{}, enc); err != nil {
+					return err
+				}
+			}
+			migrated = append(migrated, m)
+		}
+
+		if keepLegacy || skipped > 0 {
+			return nil
+		}
+		return client.Del(key).Err()
+	})
+	return
+}
+
+// Fields of the Hash that holds a mapping in the version 2 schema.
+const (
+	redisFieldOriginal    = "original"
+	redisFieldMirror      = "mirror"
+	redisFieldDepth       = "depth"
+	redisFieldForce       = "force"
+	redisFieldCredentials = "credentials"
+	redisFieldOwner       = "owner"
+	redisFieldExpires     = "expires"
+	redisFieldCreated     = "created"
+)
+
+func encodeMirrorOptions(m Mapping, options MirrorOptions, enc RedisRefEncoding) map[string]interface{} {
+	fields := map[string]interface{}{
+		redisFieldOriginal: EncodeRedisRemoteRef(m.Original, enc),
+		redisFieldMirror:   EncodeRedisRemoteRef(m.Mirror, enc),
+		redisFieldCreated:  time.Now().UTC().Format(time.RFC3339),
+	}
+
+	if options.Depth > 0 {
+		fields[redisFieldDepth] = options.Depth
+	}
+	if options.Force {
+		fields[redisFieldForce] = strconv.FormatBool(options.Force)
+	}
+	if options.Credentials != "" {
+		fields[redisFieldCredentials] = options.Credentials
+	}
+	if options.Owner != "" {
+		fields[redisFieldOwner] = options.Owner
+	}
+	if !options.Expires.IsZero() {
+		fields[redisFieldExpires] = options.Expires.UTC().Format(time.RFC3339)
+	}
+	return fields
+}
+
+func decodeMirrorOptions(fields map[string]string) (options MirrorOptions, err error) {
+	if raw, ok := fields[redisFieldDepth]; ok {
+		if options.Depth, err = strconv.Atoi(raw); err != nil {
+			return
+		}
+	}
+	if raw, ok := fields[redisFieldForce]; ok {
+		if options.Force, err = strconv.ParseBool(raw); err != nil {
+			return
+		}
+	}
+	if raw, ok := fields[redisFieldExpires]; ok {
+		if options.Expires, err = time.Parse(time.RFC3339, raw); err != nil {
+			return
+		}
+	}
+	options.Credentials = fields[redisFieldCredentials]
+	options.Owner = fields[redisFieldOwner]
+	return
+}
+
+// parseRedisMappingHashKey determines which mapping a version 2 Hash key holds the options of, and how it was encoded.
+func parseRedisMappingHashKey(key string) (m Mapping, enc RedisRefEncoding, ok bool) {
+	rawOriginal, rawMirror, ok := splitRedisMappingKey(strings.TrimPrefix(key, redisMappingPrefix))
+	if !ok {
+		return
+	}
+
+	original, enc, err := DecodeRedisRemoteRef(rawOriginal)
+	if err != nil {
+		return m, enc, false
+	}
+	mirror, _, err := DecodeRedisRemoteRef(rawMirror)
+	if err != nil {
+		return m, enc, false
+	}
+	return Mapping{Original: original, Mirror: mirror}, enc, true
+}
@@ -0,0 +1,159 @@
+package mirrorcat_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Azure/mirrorcat"
+	"github.com/go-redis/redis"
+	"github.com/spf13/viper"
+)
+
+// connectTestRedis creates a client for the Redis instance used by tests, skipping the test if it can't be reached.
+func connectTestRedis(t *testing.T) *redis.Client {
+	viper.BindEnv("redis-connection", "MIRRORCAT_REDIS_CONNECTION")
+	viper.SetDefault("redis-connection", "redis://localhost:6379")
+
+	connectionOptions, err := redis.ParseURL(viper.GetString("redis-connection"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := redis.NewClient(connectionOptions)
+	if err = client.Ping().Err(); err != nil {
+		t.Log("Unable to connect to Redis instance: ", err)
+		t.SkipNow()
+	}
+	return client
+}
+
+func TestSaveRedisMapping(t *testing.T) {
+	client := connectTestRedis(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	original := mirrorcat.RemoteRef{Repository: "schemaRepo", Ref: "master"}
+	legacy := mirrorcat.RemoteRef{Repository: "legacyMirror", Ref: "dev"}
+	indexed := mirrorcat.Mapping{Original: original, Mirror: mirrorcat.RemoteRef{Repository: "indexedMirror", Ref: "dev"}}
+	want := mirrorcat.MirrorOptions{
+		Depth:       10,
+		Force:       true,
+		Credentials: "MIRROR_TOKEN",
+		Owner:       "someone@example.com",
+		Expires:     time.Now().Add(time.Hour).Truncate(time.Second),
+	}
+
+	legacyKey := mirrorcat.RedisRemoteRef(original).String()
+	defer client.Del(legacyKey, mirrorcat.RedisIndexKey(original, mirrorcat.RedisColonEncoding), mirrorcat.RedisMappingKey(indexed, mirrorcat.RedisColonEncoding))
+
+	if err := client.SAdd(legacyKey, mirrorcat.RedisRemoteRef(legacy).String()).Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mirrorcat.SaveRedisMapping(client, indexed, want, mirrorcat.RedisColonEncoding); err != nil {
+		t.Fatal(err)
+	}
+
+	got, found, err := mirrorcat.ReadRedisOptions(client, indexed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !found {
+		t.Fatal("expected options to be found")
+	}
+	if got.Depth != want.Depth || got.Force != want.Force || got.Credentials != want.Credentials || got.Owner != want.Owner || !got.Expires.Equal(want.Expires) {
+		t.Logf("got: %+v want: %+v", got, want)
+		t.Fail()
+	}
+
+	mirrors, err := collectMirrors(ctx, mirrorcat.RedisFinder{UniversalClient: client}, original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mirrors) != 2 || mirrors[0] != "indexedMirror:dev" || mirrors[1] != "legacyMirror:dev" {
+		t.Logf("got: %v want: %v", mirrors, []string{"indexedMirror:dev", "legacyMirror:dev"})
+		t.Fail()
+	}
+
+	// A Hash which has disappeared, as it would once it expires, should also disappear from the index.
+	if err = client.Del(mirrorcat.RedisMappingKey(indexed, mirrorcat.RedisColonEncoding)).Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	remaining, err := mirrorcat.ReadRedisMappings(client, original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(remaining) != 0 {
+		t.Logf("got: %v want: none", remaining)
+		t.Fail()
+	}
+	if isMember, _ := client.SIsMember(mirrorcat.RedisIndexKey(original, mirrorcat.RedisColonEncoding), mirrorcat.RedisRemoteRef(indexed.Mirror).String()).Result(); isMember {
+		t.Log("expected the expired mirror to be removed from the index")
+		t.Fail()
+	}
+}
+
+func TestSaveRedisMapping_Expired(t *testing.T) {
+	client := connectTestRedis(t)
+
+	m := mirrorcat.Mapping{
+		Original: mirrorcat.RemoteRef{Repository: "schemaRepo", Ref: "master"},
+		Mirror:   mirrorcat.RemoteRef{Repository: "expiredMirror", Ref: "dev"},
+	}
+
+	if err := mirrorcat.SaveRedisMapping(client, m, mirrorcat.MirrorOptions{Expires: time.Now().Add(-time.Minute)}, mirrorcat.RedisColonEncoding); err == nil {
+		client.Del(mirrorcat.RedisIndexKey(m.Original, mirrorcat.RedisColonEncoding), mirrorcat.RedisMappingKey(m, mirrorcat.RedisColonEncoding))
+		t.Error("expected a mapping that has already expired to be refused")
+	}
+}
+
+func TestMigrateRedisMappings(t *testing.T) {
+	client := connectTestRedis(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	original := mirrorcat.RemoteRef{Repository: "migratedRepo", Ref: "master"}
+	mirror := mirrorcat.RemoteRef{Repository: "migratedMirror", Ref: "dev"}
+	migrated := mirrorcat.Mapping{Original: original, Mirror: mirror}
+
+	legacyKey := mirrorcat.RedisRemoteRef(original).String()
+	defer client.Del(legacyKey, mirrorcat.RedisIndexKey(original, mirrorcat.RedisColonEncoding), mirrorcat.RedisMappingKey(migrated, mirrorcat.RedisColonEncoding))
+
+	if err := client.SAdd(legacyKey, mirrorcat.RedisRemoteRef(mirror).String()).Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := mirrorcat.MigrateRedisMappings(ctx, client, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sawMapping := false
+	for _, m := range result {
+		if m == migrated {
+			sawMapping = true
+		}
+	}
+	if !sawMapping {
+		t.Logf("expected %v to be migrated, got: %v", migrated, result)
+		t.Fail()
+	}
+
+	if exists, _ := client.Exists(legacyKey).Result(); exists != 0 {
+		t.Log("expected the legacy key to be removed")
+		t.Fail()
+	}
+
+	mirrors, err := collectMirrors(ctx, mirrorcat.RedisFinder{UniversalClient: client}, original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mirrors) != 1 || mirrors[0] != "migratedMirror:dev" {
+		t.Logf("got: %v want: %v", mirrors, []string{"migratedMirror:dev"})
+		t.Fail()
+	}
+}
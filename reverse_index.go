@@ -0,0 +1,84 @@
+package mirrorcat
+
+// ReverseIndex answers which originals are pushed onto a mirror, the opposite question to the one that a
+// MirrorFinder answers. RemoteRefs are compared by their canonical form, see `RemoteRef.Canonical`.
+type ReverseIndex struct {
+	onto map[RemoteRef][]Mapping
+}
+
+// NewReverseIndex indexes `mappings` by their mirror, typically after they have been gathered from each MirrorLister
+// with `CollectMappings`.
+func NewReverseIndex(mappings []Mapping) *ReverseIndex {
+	ri := &ReverseIndex{
+		onto: make(map[RemoteRef][]Mapping, len(mappings)),
+	}
+
+	seen := make(map[Mapping]struct{}, len(mappings))
+	for _, m := range mappings {
+		if _, ok := seen[m.canonical()]; ok {
+			continue
+		}
+		seen[m.canonical()] = struct{}{}
+
+		mirror := m.Mirror.Canonical()
+		ri.onto[mirror] = append(ri.onto[mirror], m)
+	}
+	return ri
+}
+
+// MappingsOnto finds each mapping which pushes onto `mirror`. If `maxDepth` is greater than one, the mappings which
+// push onto their originals are also found, and so on, up to `maxDepth` hops away from `mirror`. This matches the
+// pushes that a TransitiveFinder with the same MaxDepth would make.
+//
+// Mappings are ordered by original, then mirror.
+func (ri *ReverseIndex) MappingsOnto(mirror RemoteRef, maxDepth int) (found []Mapping) {
+	if maxDepth < 1 {
+		maxDepth = 1
+	}
+
+	visited := map[RemoteRef]struct{}{
+		mirror.Canonical(): {},
+	}
+
+	frontier := []RemoteRef{mirror.Canonical()}
+	for depth := 0; depth < maxDepth && len(frontier) > 0; depth++ {
+		var next []RemoteRef
+
+		for _, current := range frontier {
+			for _, m := range ri.onto[current] {
+				found = append(found, m)
+
+				original := m.Original.Canonical()
+				if _, ok := visited[original]; ok {
+					continue
+				}
+				visited[original] = struct{}{}
+				next = append(next, original)
+			}
+		}
+
+		frontier = next
+	}
+
+	sortMappings(found)
+	return
+}
+
+// OriginalsOf finds each distinct original of the mappings found by `MappingsOnto`, ordered by repository then ref.
+// `mirror` itself is never included, even when it is part of a cycle.
+func (ri *ReverseIndex) OriginalsOf(mirror RemoteRef, maxDepth int) (originals []RemoteRef) {
+	seen := map[RemoteRef]struct{}{
+		mirror.Canonical(): {},
+	}
+
+	for _, m := range ri.MappingsOnto(mirror, maxDepth) {
+		if _, ok := seen[m.Original.Canonical()]; ok {
+			continue
+		}
+		seen[m.Original.Canonical()] = struct{}{}
+		originals = append(originals, m.Original)
+	}
+
+	sortRemoteRefs(originals)
+	return
+}
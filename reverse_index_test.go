@@ -0,0 +1,68 @@
+package mirrorcat_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/Azure/mirrorcat"
+)
+
+func ExampleReverseIndex_OriginalsOf() {
+	a := mirrorcat.RemoteRef{Repository: "https://github.com/Azure/mirrorcat", Ref: "master"}
+	b := mirrorcat.RemoteRef{Repository: "https://github.com/marstr/mirrorcat", Ref: "master"}
+	c := mirrorcat.RemoteRef{Repository: "https://github.com/marstr/mirrorcat", Ref: "dev"}
+
+	index := mirrorcat.NewReverseIndex([]mirrorcat.Mapping{
+		{Original: a, Mirror: b},
+		{Original: b, Mirror: c},
+	})
+
+	for _, original := range index.OriginalsOf(c, 2) {
+		fmt.Println(original.Repository, original.Ref)
+	}
+
+	// Output:
+	// https://github.com/Azure/mirrorcat master
+	// https://github.com/marstr/mirrorcat master
+}
+
+func TestReverseIndex_OriginalsOf(t *testing.T) {
+	a := mirrorcat.RemoteRef{Repository: "https://github.com/Azure/mirrorcat", Ref: "master"}
+	b := mirrorcat.RemoteRef{Repository: "https://github.com/marstr/mirrorcat", Ref: "master"}
+	c := mirrorcat.RemoteRef{Repository: "https://github.com/haydenmc/mirrorcat", Ref: "master"}
+	d := mirrorcat.RemoteRef{Repository: "https://github.com/haydenmc/mirrorcat", Ref: "dev"}
+
+	testCases := []struct {
+		name     string
+		mappings []mirrorcat.Mapping
+		mirror   mirrorcat.RemoteRef
+		depth    int
+		want     []mirrorcat.RemoteRef
+	}{
+		{"empty", nil, d, 1, nil},
+		{"direct", []mirrorcat.Mapping{{Original: a, Mirror: d}, {Original: c, Mirror: d}, {Original: a, Mirror: b}}, d, 1, []mirrorcat.RemoteRef{a, c}},
+		{"shallow", []mirrorcat.Mapping{{Original: a, Mirror: b}, {Original: b, Mirror: d}}, d, 1, []mirrorcat.RemoteRef{b}},
+		{"transitive", []mirrorcat.Mapping{{Original: a, Mirror: b}, {Original: b, Mirror: d}}, d, 2, []mirrorcat.RemoteRef{a, b}},
+		{"cycle", []mirrorcat.Mapping{{Original: a, Mirror: d}, {Original: d, Mirror: a}}, d, 5, []mirrorcat.RemoteRef{a}},
+		{"spelling", []mirrorcat.Mapping{
+			{Original: a, Mirror: mirrorcat.RemoteRef{Repository: d.Repository + ".git", Ref: "refs/heads/dev"}},
+		}, d, 1, []mirrorcat.RemoteRef{a}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := mirrorcat.NewReverseIndex(tc.mappings).OriginalsOf(tc.mirror, tc.depth)
+
+			if len(got) != len(tc.want) {
+				t.Fatalf("got: %v want: %v", got, tc.want)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Logf("got: %v want: %v", got, tc.want)
+					t.Fail()
+					break
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package mirrorcat
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// SQLMapping is a mirror, or exclusion, stored in a relational database alongside who is responsible for it, and why
+// it exists.
+type SQLMapping struct {
+	Mapping
+	Exclusion bool      `json:"exclusion,omitempty"`
+	Owner     string    `json:"owner,omitempty"`
+	Comment   string    `json:"comment,omitempty"`
+	Created   time.Time `json:"created"`
+}
+
+// SQLFinder finds mirrors in a relational database, using any `database/sql` driver whose SQL dialect accepts
+// numbered parameters like "$1". Both PostgreSQL and SQLite do.
+//
+// The tables that it reads are created by MigrateSQL.
+type SQLFinder struct {
+	*sql.DB
+}
+
+// sqlMigrations are applied in order, each exactly once, to create and update the tables used by SQLFinder.
+var sqlMigrations = []string{
+	`CREATE TABLE mirrorcat_mappings (
+		original_repo TEXT NOT NULL,
+		original_ref  TEXT NOT NULL,
+		mirror_repo   TEXT NOT NULL,
+		mirror_ref    TEXT NOT NULL,
+		exclusion     BOOLEAN NOT NULL,
+		owner         TEXT NOT NULL,
+		comment       TEXT NOT NULL,
+		created_at    TIMESTAMP NOT NULL,
+		PRIMARY KEY (original_repo, original_ref, mirror_repo, mirror_ref, exclusion)
+	)`,
+	`CREATE INDEX mirrorcat_mappings_original ON mirrorcat_mappings (original_repo, original_ref, exclusion)`,
+}
+
+// MigrateSQL creates, or updates, the tables used by SQLFinder. Each migration that has not yet been applied is run in
+// its own transaction, and recorded in the table "mirrorcat_migrations".
+//
+// Several instances of MirrorCat may start at once against the same database. If a migration fails because another
+// instance applied it first, the record of that instance having done so is treated as success.
+func MigrateSQL(ctx context.Context, db *sql.DB) (applied int, err error) {
+	// Creating the table at the same moment as another instance may fail, even though the table then exists.
+	_, createErr := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS mirrorcat_migrations (
+		version    INTEGER PRIMARY KEY,
+		applied_at TIMESTAMP NOT NULL
+	)`)
+
+	var current int
+	if err = db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM mirrorcat_migrations`).Scan(&current); err != nil {
+		if createErr != nil {
+			err = createErr
+		}
+		return
+	}
+
+	for version := current + 1; version <= len(sqlMigrations); version++ {
+		var ran bool
+		if ran, err = applySQLMigration(ctx, db, version); err != nil {
+			err = fmt.Errorf("unable to apply migration %d: %v", version, err)
+			return
+		}
+		if ran {
+			applied++
+		}
+	}
+	return
+}
+
+// applySQLMigration runs a single migration. If it fails, but has since been recorded as applied by another instance
+// of MirrorCat, `ran` is false and no error is returned.
+func applySQLMigration(ctx context.Context, db *sql.DB, version int) (ran bool, err error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return
+	}
+
+	if _, err = tx.ExecContext(ctx, sqlMigrations[version-1]); err == nil {
+		_, err = tx.ExecContext(ctx, `INSERT INTO mirrorcat_migrations (version, applied_at) VALUES ($1, $2)`, version, time.Now().UTC())
+	}
+
+	if err != nil {
+		tx.Rollback()
+	} else if err = tx.Commit(); err == nil {
+		return true, nil
+	}
+
+	var recorded int
+	if db.QueryRowContext(ctx, `SELECT COUNT(*) FROM mirrorcat_migrations WHERE version = $1`, version).Scan(&recorded) == nil && recorded > 0 {
+		err = nil
+	}
+	return
+}
+
+// FindMirrors publishes each mirror of `original` stored in the database, ordered by mirror.
+func (sf SQLFinder) FindMirrors(ctx context.Context, original RemoteRef, results chan<- RemoteRef) error {
+	return sf.findTargets(ctx, original, false, results)
+}
+
+// FindExclusions publishes each exclusion of `original` stored in the database, ordered by mirror.
+func (sf SQLFinder) FindExclusions(ctx context.Context, original RemoteRef, results chan<- RemoteRef) error {
+	return sf.findTargets(ctx, original, true, results)
+}
+
+// ListMirrors publishes every mirror stored in the database, ordered by original.
+func (sf SQLFinder) ListMirrors(ctx context.Context, results chan<- Mapping) error {
+	return sf.listMappings(ctx, false, results)
+}
+
+// ListExclusions publishes every exclusion stored in the database, ordered by original.
+func (sf SQLFinder) ListExclusions(ctx context.Context, results chan<- Mapping) error {
+	return sf.listMappings(ctx, true, results)
+}
+
+// ReadSQLMappings reads every mirror and exclusion stored in the database, along with who owns them and why, ordered
+// by original.
+func ReadSQLMappings(ctx context.Context, db *sql.DB) (mappings []SQLMapping, err error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT original_repo, original_ref, mirror_repo, mirror_ref, exclusion, owner, comment, created_at
+		FROM mirrorcat_mappings
+		ORDER BY original_repo, original_ref, exclusion, mirror_repo, mirror_ref`)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var m SQLMapping
+		err = rows.Scan(&m.Original.Repository, &m.Original.Ref, &m.Mirror.Repository, &m.Mirror.Ref, &m.Exclusion, &m.Owner, &m.Comment, &m.Created)
+		if err != nil {
+			return
+		}
+		mappings = append(mappings, m)
+	}
+	err = rows.Err()
+	return
+}
+
+// SaveSQLMapping stores a mirror, or exclusion, replacing who owns it and why if it was already stored. A mapping that
+// was already stored keeps the time it was created. Otherwise, `m.Created` is used, or the current time if it is not set.
+func SaveSQLMapping(ctx context.Context, db *sql.DB, m SQLMapping) error {
+	if m.Created.IsZero() {
+		m.Created = time.Now()
+	}
+
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO mirrorcat_mappings (original_repo, original_ref, mirror_repo, mirror_ref, exclusion, owner, comment, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (original_repo, original_ref, mirror_repo, mirror_ref, exclusion)
+		DO UPDATE SET owner = excluded.owner, comment = excluded.comment`,
+		m.Original.Repository, m.Original.Ref, m.Mirror.Repository, m.Mirror.Ref, m.Exclusion, m.Owner, m.Comment, m.Created.UTC())
+	return err
+}
+
+// DeleteSQLMapping removes a mirror, or exclusion, from the database. If it was not stored, `found` is false.
+func DeleteSQLMapping(ctx context.Context, db *sql.DB, m Mapping, exclusion bool) (found bool, err error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return
+	}
+
+	if found, err = deleteSQLMapping(ctx, tx, m, exclusion); err != nil {
+		tx.Rollback()
+		return
+	}
+
+	err = tx.Commit()
+	return
+}
+
+func deleteSQLMapping(ctx context.Context, tx *sql.Tx, m Mapping, exclusion bool) (found bool, err error) {
+	result, err := tx.ExecContext(ctx, `
+		DELETE FROM mirrorcat_mappings
+		WHERE original_repo = $1 AND original_ref = $2 AND mirror_repo = $3 AND mirror_ref = $4 AND exclusion = $5`,
+		m.Original.Repository, m.Original.Ref, m.Mirror.Repository, m.Mirror.Ref, exclusion)
+	if err != nil {
+		return
+	}
+
+	affected, err := result.RowsAffected()
+	found = affected > 0
+	return
+}
+
+func (sf SQLFinder) findTargets(ctx context.Context, original RemoteRef, exclusion bool, results chan<- RemoteRef) error {
+	defer close(results)
+
+	rows, err := sf.QueryContext(ctx, `
+		SELECT mirror_repo, mirror_ref
+		FROM mirrorcat_mappings
+		WHERE original_repo = $1 AND original_ref = $2 AND exclusion = $3
+		ORDER BY mirror_repo, mirror_ref`,
+		original.Repository, original.Ref, exclusion)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var target RemoteRef
+		if err = rows.Scan(&target.Repository, &target.Ref); err != nil {
+			return err
+		}
+
+		select {
+		case results <- target:
+			// Intentionally Left Blank
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return rows.Err()
+}
+
+func (sf SQLFinder) listMappings(ctx context.Context, exclusion bool, results chan<- Mapping) error {
+	defer close(results)
+
+	rows, err := sf.QueryContext(ctx, `
+		SELECT original_repo, original_ref, mirror_repo, mirror_ref
+		FROM mirrorcat_mappings
+		WHERE exclusion = $1
+		ORDER BY original_repo, original_ref, mirror_repo, mirror_ref`,
+		exclusion)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var m Mapping
+		if err = rows.Scan(&m.Original.Repository, &m.Original.Ref, &m.Mirror.Repository, &m.Mirror.Ref); err != nil {
+			return err
+		}
+
+		select {
+		case results <- m:
+			// Intentionally Left Blank
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,208 @@
+package mirrorcat_test
+
+import (
+	"context"
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Azure/mirrorcat"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func openSQLite(t *testing.T) (db *sql.DB, cleanup func()) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "mirrorcat")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err = sql.Open("sqlite3", filepath.Join(dir, "mirrorcat.db"))
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+
+	return db, func() {
+		db.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestMigrateSQL(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	db, cleanup := openSQLite(t)
+	defer cleanup()
+
+	applied, err := mirrorcat.MigrateSQL(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if applied == 0 {
+		t.Log("no migrations were applied to an empty database")
+		t.Fail()
+	}
+
+	applied, err = mirrorcat.MigrateSQL(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if applied != 0 {
+		t.Logf("got: %d want: 0 migrations applied a second time", applied)
+		t.Fail()
+	}
+}
+
+func TestMigrateSQL_concurrent(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	reference, cleanup := openSQLite(t)
+	defer cleanup()
+
+	want, err := mirrorcat.MigrateSQL(ctx, reference)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "mirrorcat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Each instance of MirrorCat has its own connections to the database.
+	const instances = 4
+	applied, errs := make(chan int, instances), make(chan error, instances)
+	for i := 0; i < instances; i++ {
+		db, err := sql.Open("sqlite3", filepath.Join(dir, "mirrorcat.db")+"?_busy_timeout=5000")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		go func() {
+			count, err := mirrorcat.MigrateSQL(ctx, db)
+			applied <- count
+			errs <- err
+		}()
+	}
+
+	got := 0
+	for i := 0; i < instances; i++ {
+		got += <-applied
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	}
+
+	if got != want {
+		t.Logf("got: %d want: %d migrations applied between every instance", got, want)
+		t.Fail()
+	}
+}
+
+func TestSQLFinder(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	db, cleanup := openSQLite(t)
+	defer cleanup()
+
+	if _, err := mirrorcat.MigrateSQL(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+
+	original := mirrorcat.RemoteRef{Repository: "https://github.com/Azure/mirrorcat.git", Ref: "master"}
+	mirror := mirrorcat.Mapping{
+		Original: original,
+		Mirror:   mirrorcat.RemoteRef{Repository: "https://github.com/marstr/mirrorcat.git", Ref: "master"},
+	}
+	excluded := mirrorcat.Mapping{
+		Original: original,
+		Mirror:   mirrorcat.RemoteRef{Repository: "https://github.com/haydenmc/mirrorcat.git", Ref: "master"},
+	}
+
+	created := time.Date(2018, time.March, 1, 12, 0, 0, 0, time.UTC)
+	for _, m := range []mirrorcat.SQLMapping{
+		{Mapping: mirror, Owner: "marstr", Comment: "first draft", Created: created},
+		{Mapping: mirror, Owner: "marstr", Comment: "A fork for testing.", Created: created.Add(time.Hour)},
+		{Mapping: excluded, Exclusion: true, Owner: "haydenmc"},
+	} {
+		if err := mirrorcat.SaveSQLMapping(ctx, db, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	subject := mirrorcat.SQLFinder{DB: db}
+
+	got, err := collectMirrors(ctx, subject, original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0] != "https://github.com/marstr/mirrorcat.git:master" {
+		t.Logf("got: %v want: [https://github.com/marstr/mirrorcat.git:master]", got)
+		t.Fail()
+	}
+
+	listed, err := mirrorcat.CollectMappings(ctx, subject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listed) != 1 || listed[0] != mirror {
+		t.Logf("got: %v want: [%v]", listed, mirror)
+		t.Fail()
+	}
+
+	stored, err := mirrorcat.ReadSQLMappings(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 2 {
+		t.Fatalf("got: %d want: 2 mappings stored", len(stored))
+	}
+	if got := stored[0]; got.Mapping != mirror || got.Exclusion || got.Comment != "A fork for testing." || !got.Created.Equal(created) {
+		t.Logf("got: %#v want: the mirror, with its replaced comment and original creation time", got)
+		t.Fail()
+	}
+	if got := stored[1]; got.Mapping != excluded || !got.Exclusion || got.Owner != "haydenmc" || got.Created.IsZero() {
+		t.Logf("got: %#v want: the exclusion, with a creation time", got)
+		t.Fail()
+	}
+
+	found, err := mirrorcat.DeleteSQLMapping(ctx, db, excluded, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found {
+		t.Log("an exclusion was deleted as though it were a mirror")
+		t.Fail()
+	}
+
+	found, err = mirrorcat.DeleteSQLMapping(ctx, db, excluded, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !found {
+		t.Log("the exclusion was not found")
+		t.Fail()
+	}
+
+	results, errs := make(chan mirrorcat.RemoteRef), make(chan error, 1)
+	go func() {
+		errs <- subject.FindExclusions(ctx, original, results)
+	}()
+	for exclusion := range results {
+		t.Logf("unexpected exclusion: %v", exclusion)
+		t.Fail()
+	}
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+}
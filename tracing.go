@@ -0,0 +1,59 @@
+package mirrorcat
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracerName identifies the spans that MirrorCat creates. Spans are only recorded once a TracerProvider has been
+// registered with `otel.SetTracerProvider`.
+const TracerName = "github.com/Azure/mirrorcat"
+
+// These are the attributes that are attached to MirrorCat's spans. Like log fields, they never hold credentials.
+const (
+	AttributeDelivery = attribute.Key("mirrorcat.delivery")
+	AttributeOriginal = attribute.Key("mirrorcat.original")
+	AttributeMirror   = attribute.Key("mirrorcat.mirror")
+	AttributeFinder   = attribute.Key("mirrorcat.finder")
+	AttributeStage    = attribute.Key("mirrorcat.stage")
+)
+
+// StartSpan begins a span as a child of the span in `ctx`, if there is one. The returned context holds the new span.
+func StartSpan(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(TracerName).Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+// EndSpan marks a span as failed if `err` is not nil, then ends it. Credentials are removed from the error before it
+// is recorded.
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.SetStatus(codes.Error, RedactCredentials(err.Error()))
+	}
+	span.End()
+}
+
+// findMirrorsTraced asks a child of a MergeFinder or ParallelMergeFinder for mirrors within a span of its own.
+func findMirrorsTraced(ctx context.Context, finder MirrorFinder, needle RemoteRef, results chan<- RemoteRef) error {
+	ctx, span := StartSpan(ctx, "FindMirrors", AttributeFinder.String(finderName(finder)), AttributeOriginal.String(LogRef(needle)))
+	err := finder.FindMirrors(ctx, needle, results)
+	EndSpan(span, err)
+	return err
+}
+
+// finderName describes a MirrorFinder in a span. The Name of an InstrumentedFinder is used if there is one, otherwise
+// the MirrorFinder's type is.
+func finderName(finder MirrorFinder) string {
+	switch f := finder.(type) {
+	case InstrumentedFinder:
+		return f.Name
+	case PriorityFinder:
+		return finderName(f.MirrorFinder)
+	default:
+		return fmt.Sprintf("%T", finder)
+	}
+}
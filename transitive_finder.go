@@ -0,0 +1,105 @@
+package mirrorcat
+
+import "context"
+
+// TransitiveFinder decorates a MirrorFinder, so that it not only finds the mirrors of an original, but
+// also the mirrors of those mirrors, and so on. This allows a chain of mappings like A -> B -> C to be
+// satisfied by pushing the same commits from A to both B and C.
+//
+// Expansion stops after MaxDepth hops away from the original. Each RemoteRef is published at most once,
+// and the original itself is never published, so cycles in the underlying mappings are harmless.
+type TransitiveFinder struct {
+	MirrorFinder
+	MaxDepth int
+}
+
+// FindMirrors performs a breadth-first search, starting at `original`, of the graph described by the
+// underlying MirrorFinder.
+func (tf TransitiveFinder) FindMirrors(ctx context.Context, original RemoteRef, results chan<- RemoteRef) error {
+	defer close(results)
+
+	mappings := make(chan Mapping)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- tf.FindMappings(ctx, original, mappings)
+	}()
+
+	for m := range mappings {
+		select {
+		case results <- m.Mirror:
+			// Intentionally Left Blank
+		case <-ctx.Done():
+			// FindMappings stops publishing once it notices that the context has been cancelled.
+		}
+	}
+	return <-errs
+}
+
+// FindMappings behaves like FindMirrors, but publishes the mapping through which each mirror was reached. That is,
+// the Original of each mapping is either `original` itself, or the mirror one hop closer to it. A mirror reached
+// by more than one path is published once, through the mapping that is the fewest hops away from `original`.
+//
+// If the underlying MirrorFinder returns a MergeError, the mirrors that it did find are still followed, and a
+// MergeError describing every failure is returned once the search has finished.
+func (tf TransitiveFinder) FindMappings(ctx context.Context, original RemoteRef, results chan<- Mapping) error {
+	defer close(results)
+
+	maxDepth := tf.MaxDepth
+	if maxDepth < 1 {
+		maxDepth = 1
+	}
+
+	visited := map[RemoteRef]struct{}{
+		original.Canonical(): {},
+	}
+
+	var failures MergeError
+
+	frontier := []RemoteRef{original}
+	for depth := 0; depth < maxDepth && len(frontier) > 0; depth++ {
+		var next []RemoteRef
+
+		for _, current := range frontier {
+			intermediate := make(chan RemoteRef)
+			errs := make(chan error, 1)
+
+			go func(current RemoteRef) {
+				errs <- tf.MirrorFinder.FindMirrors(ctx, current, intermediate)
+			}(current)
+
+			for mirror := range intermediate {
+				key := mirror.Canonical()
+				if _, ok := visited[key]; ok {
+					continue
+				}
+				visited[key] = struct{}{}
+				next = append(next, mirror)
+
+				select {
+				case results <- Mapping{Original: current, Mirror: mirror}:
+					// Intentionally Left Blank
+				case <-ctx.Done():
+					for range intermediate {
+						// Drained, so that a MirrorFinder which is still publishing isn't blocked forever.
+					}
+					return ctx.Err()
+				}
+			}
+
+			if err := <-errs; err != nil {
+				partial, ok := err.(MergeError)
+				if !ok {
+					return err
+				}
+				failures = append(failures, partial...)
+			}
+		}
+
+		frontier = next
+	}
+
+	if len(failures) > 0 {
+		return failures
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+package mirrorcat_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Azure/mirrorcat"
+)
+
+func ExampleTransitiveFinder() {
+	a := mirrorcat.RemoteRef{Repository: "https://github.com/Azure/mirrorcat", Ref: "master"}
+	b := mirrorcat.RemoteRef{Repository: "https://github.com/marstr/mirrorcat", Ref: "master"}
+	c := mirrorcat.RemoteRef{Repository: "https://github.com/haydenmc/mirrorcat", Ref: "master"}
+
+	inner := mirrorcat.NewDefaultMirrorFinder()
+	inner.AddMirrors(a, b)
+	inner.AddMirrors(b, c)
+
+	subject := mirrorcat.TransitiveFinder{
+		MirrorFinder: inner,
+		MaxDepth:     5,
+	}
+
+	results := make(chan mirrorcat.RemoteRef)
+	go subject.FindMirrors(context.Background(), a, results)
+
+	for result := range results {
+		fmt.Println(result.Repository, result.Ref)
+	}
+
+	// Output:
+	// https://github.com/marstr/mirrorcat master
+	// https://github.com/haydenmc/mirrorcat master
+}
+
+func TestTransitiveFinder_FindMirrors(t *testing.T) {
+	a := mirrorcat.RemoteRef{Repository: "https://github.com/Azure/mirrorcat", Ref: "master"}
+	b := mirrorcat.RemoteRef{Repository: "https://github.com/marstr/mirrorcat", Ref: "master"}
+	c := mirrorcat.RemoteRef{Repository: "https://github.com/haydenmc/mirrorcat", Ref: "master"}
+	d := mirrorcat.RemoteRef{Repository: "https://github.com/haydenmc/mirrorcat", Ref: "dev"}
+
+	inner := mirrorcat.NewDefaultMirrorFinder()
+	inner.AddMirrors(a, b, c)
+	inner.AddMirrors(b, c, a)
+	inner.AddMirrors(c, d)
+
+	testCases := []struct {
+		depth int
+		want  []mirrorcat.RemoteRef
+	}{
+		{0, []mirrorcat.RemoteRef{b, c}},
+		{1, []mirrorcat.RemoteRef{b, c}},
+		{2, []mirrorcat.RemoteRef{b, c, d}},
+		{10, []mirrorcat.RemoteRef{b, c, d}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprint(tc.depth), func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+			defer cancel()
+
+			subject := mirrorcat.TransitiveFinder{
+				MirrorFinder: inner,
+				MaxDepth:     tc.depth,
+			}
+
+			results, errs := make(chan mirrorcat.RemoteRef), make(chan error, 1)
+			go func() {
+				errs <- subject.FindMirrors(ctx, a, results)
+			}()
+
+			var got []mirrorcat.RemoteRef
+			for result := range results {
+				got = append(got, result)
+			}
+
+			if err := <-errs; err != nil {
+				t.Error(err)
+			}
+
+			if fmt.Sprint(got) != fmt.Sprint(tc.want) {
+				t.Logf("\ngot:  %v\nwant: %v", got, tc.want)
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestTransitiveFinder_FindMappings(t *testing.T) {
+	a := mirrorcat.RemoteRef{Repository: "https://github.com/Azure/mirrorcat", Ref: "master"}
+	b := mirrorcat.RemoteRef{Repository: "https://github.com/marstr/mirrorcat", Ref: "master"}
+	c := mirrorcat.RemoteRef{Repository: "https://github.com/haydenmc/mirrorcat", Ref: "master"}
+	d := mirrorcat.RemoteRef{Repository: "https://github.com/haydenmc/mirrorcat", Ref: "dev"}
+
+	inner := mirrorcat.NewDefaultMirrorFinder()
+	inner.AddMirrors(a, b)
+	inner.AddMirrors(b, c, d)
+	inner.AddMirrors(c, d)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	subject := mirrorcat.TransitiveFinder{
+		MirrorFinder: inner,
+		MaxDepth:     5,
+	}
+
+	results, errs := make(chan mirrorcat.Mapping), make(chan error, 1)
+	go func() {
+		errs <- subject.FindMappings(ctx, a, results)
+	}()
+
+	var got []mirrorcat.Mapping
+	for result := range results {
+		got = append(got, result)
+	}
+
+	if err := <-errs; err != nil {
+		t.Error(err)
+	}
+
+	want := []mirrorcat.Mapping{
+		{Original: a, Mirror: b},
+		{Original: b, Mirror: c},
+		{Original: b, Mirror: d},
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Logf("\ngot:  %v\nwant: %v", got, want)
+		t.Fail()
+	}
+}
+
+func TestTransitiveFinder_FindMappings_partial(t *testing.T) {
+	a := mirrorcat.RemoteRef{Repository: "https://github.com/Azure/mirrorcat", Ref: "master"}
+	b := mirrorcat.RemoteRef{Repository: "https://github.com/marstr/mirrorcat", Ref: "master"}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	// Every lookup finds b, and reports that one of the merged sources failed.
+	subject := mirrorcat.TransitiveFinder{
+		MirrorFinder: failingFinder{
+			mirrors: []mirrorcat.RemoteRef{b},
+			err:     mirrorcat.MergeError{errors.New("unreachable")},
+		},
+		MaxDepth: 5,
+	}
+
+	results, errs := make(chan mirrorcat.Mapping), make(chan error, 1)
+	go func() {
+		errs <- subject.FindMappings(ctx, a, results)
+	}()
+
+	var got []mirrorcat.Mapping
+	for result := range results {
+		got = append(got, result)
+	}
+
+	if want := []mirrorcat.Mapping{{Original: a, Mirror: b}}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Logf("\ngot:  %v\nwant: %v", got, want)
+		t.Fail()
+	}
+
+	if merged, ok := (<-errs).(mirrorcat.MergeError); !ok || len(merged) != 2 {
+		t.Logf("got: %v want: a MergeError from each of the 2 lookups", merged)
+		t.Fail()
+	}
+}
+
+// stubbornFinder is a MirrorFinder that publishes every one of its mirrors, even after it has been cancelled.
+type stubbornFinder struct {
+	mirrors []mirrorcat.RemoteRef
+	done    chan struct{}
+}
+
+func (sf stubbornFinder) FindMirrors(ctx context.Context, original mirrorcat.RemoteRef, results chan<- mirrorcat.RemoteRef) error {
+	defer close(sf.done)
+	defer close(results)
+	for _, m := range sf.mirrors {
+		results <- m
+	}
+	return nil
+}
+
+func TestTransitiveFinder_FindMappings_cancelled(t *testing.T) {
+	a := mirrorcat.RemoteRef{Repository: "https://github.com/Azure/mirrorcat", Ref: "master"}
+	b := mirrorcat.RemoteRef{Repository: "https://github.com/marstr/mirrorcat", Ref: "master"}
+	c := mirrorcat.RemoteRef{Repository: "https://github.com/haydenmc/mirrorcat", Ref: "master"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	inner := stubbornFinder{
+		mirrors: []mirrorcat.RemoteRef{b, c, {Repository: "https://github.com/Azure/azure-sdk-for-go", Ref: "master"}},
+		done:    make(chan struct{}),
+	}
+	subject := mirrorcat.TransitiveFinder{MirrorFinder: inner, MaxDepth: 1}
+
+	results, errs := make(chan mirrorcat.Mapping), make(chan error, 1)
+	go func() {
+		errs <- subject.FindMappings(ctx, a, results)
+	}()
+
+	<-results
+	cancel()
+
+	if err := <-errs; err != context.Canceled {
+		t.Errorf("got: %v want: %v", err, context.Canceled)
+	}
+
+	select {
+	case <-inner.done:
+		// Intentionally Left Blank
+	case <-time.After(3 * time.Second):
+		t.Error("the inner MirrorFinder was left blocked while publishing")
+	}
+}
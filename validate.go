@@ -0,0 +1,104 @@
+package mirrorcat
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// scpLikeRepository matches the short-hand that git accepts for SSH remotes, for example
+// "git@github.com:Azure/mirrorcat.git".
+var scpLikeRepository = regexp.MustCompile(`^[A-Za-z0-9._~-]+@[A-Za-z0-9.-]+:[^/].*$`)
+
+// ValidateRepository ensures that a string could plausibly be handed to git as the location of a remote repository.
+// Accepted forms are URLs with a scheme and host, SCP-like SSH locations, and absolute paths on the local file system.
+func ValidateRepository(repo string) error {
+	if repo == "" {
+		return errors.New("repository must not be empty")
+	}
+
+	if strings.ContainsAny(repo, " \t\r\n") {
+		return fmt.Errorf("repository %q must not contain whitespace", repo)
+	}
+
+	if scpLikeRepository.MatchString(repo) || filepath.IsAbs(repo) {
+		return nil
+	}
+
+	parsed, err := url.Parse(repo)
+	if err != nil {
+		return fmt.Errorf("repository %q is not a valid URL: %v", repo, err)
+	}
+
+	switch parsed.Scheme {
+	case "http", "https", "git", "ssh":
+		if parsed.Host == "" {
+			return fmt.Errorf("repository %q is missing a host", repo)
+		}
+	case "file":
+		if parsed.Path == "" {
+			return fmt.Errorf("repository %q is missing a path", repo)
+		}
+	case "":
+		return fmt.Errorf("repository %q is missing a scheme", repo)
+	default:
+		return fmt.Errorf("repository %q uses unsupported scheme %q", repo, parsed.Scheme)
+	}
+	return nil
+}
+
+// ValidateRef ensures that a string is shaped like a ref name that git would accept.
+// The rules checked are a subset of those enforced by `git check-ref-format`.
+func ValidateRef(ref string) error {
+	return validateRef(ref, " ~^:?*[\\")
+}
+
+// ValidateRefFor behaves like ValidateRef, but only rejects the characters that `enc` is unable to store in Redis.
+// RedisJSONEncoding is able to store a ref containing a colon, so such refs are accepted.
+func ValidateRefFor(ref string, enc RedisRefEncoding) error {
+	if enc == RedisJSONEncoding {
+		return validateRef(ref, " ~^?*[\\")
+	}
+	return ValidateRef(ref)
+}
+
+// validateRef checks the rules of ValidateRef, rejecting each character in `disallowed`.
+func validateRef(ref, disallowed string) error {
+	if ref == "" {
+		return errors.New("ref must not be empty")
+	}
+
+	if strings.HasPrefix(ref, "-") || strings.HasPrefix(ref, "/") || strings.HasSuffix(ref, "/") || strings.HasSuffix(ref, ".") || strings.HasSuffix(ref, ".lock") {
+		return fmt.Errorf("ref %q is not a valid ref name", ref)
+	}
+
+	if strings.Contains(ref, "..") || strings.Contains(ref, "//") || strings.Contains(ref, "@{") {
+		return fmt.Errorf("ref %q is not a valid ref name", ref)
+	}
+
+	for _, r := range ref {
+		if r < 0x20 || r == 0x7f || strings.ContainsRune(disallowed, r) {
+			return fmt.Errorf("ref %q contains the disallowed character %q", ref, r)
+		}
+	}
+	return nil
+}
+
+// Validate ensures that both the Repository and Ref portions of a RemoteRef are well-formed.
+func (rr RemoteRef) Validate() error {
+	if err := ValidateRepository(rr.Repository); err != nil {
+		return err
+	}
+	return ValidateRef(rr.Ref)
+}
+
+// ValidateFor behaves like Validate, but checks the Ref with ValidateRefFor.
+func (rr RemoteRef) ValidateFor(enc RedisRefEncoding) error {
+	if err := ValidateRepository(rr.Repository); err != nil {
+		return err
+	}
+	return ValidateRefFor(rr.Ref, enc)
+}